@@ -8,11 +8,19 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+
+	"whatsapp-panel/internal/config"
 )
 
 func main() {
+	// Usar o mesmo diretório de sessões do servidor
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Erro ao carregar configurações: %v", err)
+	}
+
 	// Limpar todas as sessions e sessions_old
-	dirs := []string{"storage/sessions", "storage/sessions_old"}
+	dirs := []string{cfg.SessionsDir, "storage/sessions_old"}
 
 	for _, dir := range dirs {
 		// Verificar se diretório existe
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"whatsapp-panel/internal/config"
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/services/whatsapp"
+	"whatsapp-panel/internal/storage"
+)
+
+const usage = `Uso: bundle <comando> [opções]
+
+Comandos:
+  export -id ID -out ARQUIVO [-tenant ID] [-password SENHA]
+                                        exporta a sessão para um pacote portátil
+  import -in ARQUIVO [-tenant ID] [-password SENHA]
+                                        importa um pacote; a sessão é conectada
+                                        quando o servidor for (re)iniciado
+
+A senha também pode ser informada pela variável BUNDLE_PASSWORD.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	id := flags.String("id", "", "ID da sessão")
+	tenantID := flags.String("tenant", models.DefaultTenantID, "ID do workspace")
+	in := flags.String("in", "", "pacote a importar")
+	out := flags.String("out", "", "arquivo de destino do pacote")
+	password := flags.String("password", os.Getenv("BUNDLE_PASSWORD"), "senha do pacote (vazio = sem cifra)")
+	flags.Parse(os.Args[2:])
+
+	// Usar o mesmo banco e diretório de sessões do servidor
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Println("Erro ao carregar configurações:", err)
+		os.Exit(1)
+	}
+
+	db, err := storage.NewDatabase(cfg.DatabasePath)
+	if err != nil {
+		fmt.Println("Erro ao abrir banco de dados:", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	mgr, err := whatsapp.NewManager(cfg, db)
+	if err != nil {
+		fmt.Println("Erro ao criar gerenciador WhatsApp:", err)
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "export":
+		if *id == "" || *out == "" {
+			fmt.Println("Informe -id e -out")
+			os.Exit(1)
+		}
+		f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			fmt.Println("Erro ao criar arquivo:", err)
+			os.Exit(1)
+		}
+		if err := whatsapp.ExportBundle(db, mgr.Sessions, *tenantID, *id, *password, f); err != nil {
+			f.Close()
+			os.Remove(*out)
+			fmt.Println("Erro ao exportar sessão:", err)
+			os.Exit(1)
+		}
+		if err := f.Close(); err != nil {
+			fmt.Println("Erro ao gravar pacote:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Sessão %s exportada para %s\n", *id, *out)
+
+	case "import":
+		if *in == "" {
+			fmt.Println("Informe -in")
+			os.Exit(1)
+		}
+		f, err := os.Open(*in)
+		if err != nil {
+			fmt.Println("Erro ao abrir pacote:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+
+		if err := mgr.CheckQuota(*tenantID); err != nil {
+			fmt.Println("Erro ao importar sessão:", err)
+			os.Exit(1)
+		}
+		session, err := whatsapp.ImportBundle(db, mgr.Sessions, *tenantID, f, *password)
+		if err != nil {
+			fmt.Println("Erro ao importar sessão:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Sessão %s (+%s) importada no workspace %s\n", session.ID, session.PhoneNumber, *tenantID)
+
+	default:
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+}
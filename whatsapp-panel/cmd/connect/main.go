@@ -6,6 +6,8 @@ import (
 	"os"
 	"time"
 
+	"whatsapp-panel/internal/config"
+	"whatsapp-panel/internal/models"
 	"whatsapp-panel/internal/services/whatsapp"
 	"whatsapp-panel/internal/storage"
 
@@ -13,8 +15,15 @@ import (
 )
 
 func main() {
-	// Inicializar banco de dados para sessão de conexão
-	db, err := storage.NewDatabase("connect.db")
+	// Carregar configurações (diretório de sessões compartilhado com o servidor)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Println("Erro ao carregar configurações:", err)
+		os.Exit(1)
+	}
+
+	// Banco do painel: a sessão pareada é cadastrada como as criadas pelo servidor
+	db, err := storage.NewDatabase(cfg.DatabasePath)
 	if err != nil {
 		fmt.Println("Erro ao abrir banco de dados:", err)
 		os.Exit(1)
@@ -22,8 +31,12 @@ func main() {
 	defer db.Close()
 
 	// Criar gerenciador e cliente WhatsApp
-	mgr := whatsapp.NewManager(db)
-	client, err := mgr.NewClient()
+	mgr, err := whatsapp.NewManager(cfg, db)
+	if err != nil {
+		fmt.Println("Erro ao criar gerenciador WhatsApp:", err)
+		os.Exit(1)
+	}
+	client, err := mgr.NewClient(models.DefaultTenantID)
 	if err != nil {
 		fmt.Println("Erro ao criar cliente WhatsApp:", err)
 		os.Exit(1)
@@ -72,6 +85,10 @@ func main() {
 		time.Sleep(1 * time.Second)
 	}
 
-	// Sessão estabelecida, finalize
+	// Sessão estabelecida, finalize gravando o store (cifrado, se houver chave)
+	if err := mgr.Sessions.Close(client.ID, client.Store); err != nil {
+		fmt.Println("Erro ao gravar sessão:", err)
+		os.Exit(1)
+	}
 	fmt.Println("Sessão de WhatsApp ativa. Execute o comando de sincronização quando desejar.")
 }
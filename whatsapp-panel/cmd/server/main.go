@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"html/template"
 	"log"
+	"net"
+	"net/http"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -26,6 +33,21 @@ func dict(values ...interface{}) map[string]interface{} {
 	return m
 }
 
+// cookieSecret retorna o segredo do cookie de sessão (COOKIE_SECRET) ou, se
+// não configurado, um aleatório: o navegador precisa informar a chave de API
+// de novo após cada reinício
+func cookieSecret(configured string) ([]byte, error) {
+	if configured != "" {
+		return []byte(configured), nil
+	}
+	log.Println("Aviso: COOKIE_SECRET não configurado, usando segredo aleatório")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
 func main() {
 	// Carregar configurações
 	cfg, err := config.LoadConfig()
@@ -38,13 +60,57 @@ func main() {
 	if err != nil {
 		log.Fatalf("Erro ao inicializar banco de dados: %v", err)
 	}
-	defer db.Close()
+
+	// Sem chave de administrador, o painel só é acessível pelas chaves dos
+	// workspaces: recusar iniciar se não houver nenhuma
+	if cfg.AdminAPIKey == "" {
+		keys, err := db.CountTenantKeys()
+		if err != nil {
+			log.Fatalf("Erro ao verificar chaves dos workspaces: %v", err)
+		}
+		if keys == 0 {
+			log.Fatalf("ADMIN_API_KEY não configurada e nenhum workspace tem chave de API: defina ADMIN_API_KEY ou crie um workspace com \"go run ./cmd/tenants create -id ID -name NOME\"")
+		}
+		log.Println("Aviso: ADMIN_API_KEY não configurada: apenas as chaves dos workspaces dão acesso ao painel e as rotas de operador (/reconcile) ficam desativadas")
+	}
 
 	// Inicializar gerenciador de clientes WhatsApp
-	waManager := whatsapp.NewManager(db)
+	waManager, err := whatsapp.NewManager(cfg, db)
+	if err != nil {
+		log.Fatalf("Erro ao inicializar gerenciador WhatsApp: %v", err)
+	}
+
+	// Restaurar sessões pareadas a partir do armazenamento
+	report, err := waManager.RestoreSessions()
+	if err != nil {
+		log.Printf("Erro ao restaurar sessões: %v", err)
+	} else {
+		log.Printf("Sessões restauradas: %d", len(report.Restored))
+		for _, failure := range report.Failed {
+			log.Printf("Sessão não restaurada (%s): %s", failure.File, failure.Reason)
+		}
+	}
+
+	// Reconciliar sessões órfãs periodicamente, já com as restauradas no gerenciador
+	waManager.StartReconciler()
+
+	// Entregar a fila de envio, incluindo mensagens pendentes antes do reinício
+	if err := waManager.StartOutbox(); err != nil {
+		log.Fatalf("Erro ao iniciar fila de envio: %v", err)
+	}
+
+	// Retomar as campanhas que estavam em andamento
+	if err := waManager.StartCampaigns(); err != nil {
+		log.Fatalf("Erro ao iniciar campanhas: %v", err)
+	}
+
+	// Executar os agendamentos, incluindo os que venceram com o servidor parado
+	if err := waManager.StartScheduler(); err != nil {
+		log.Fatalf("Erro ao iniciar agendador: %v", err)
+	}
 
 	// Inicializar handlers
-	authHandler := handlers.NewAuthHandler()
+	authHandler := handlers.NewAuthHandler(db, cfg.AdminAPIKey)
 	sessionHandler := handlers.NewSessionHandler(waManager, db)
 	whatsappHandler := handlers.NewWhatsAppHandler(waManager, db)
 
@@ -59,12 +125,13 @@ func main() {
 	// Configurar funções auxiliares para templates
 	router.SetFuncMap(template.FuncMap{
 		"dict": dict,
+		"join": strings.Join,
 	})
 
 	// Configurar CORS
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
@@ -72,7 +139,12 @@ func main() {
 	}))
 
 	// Configurar sessões
-	store := cookie.NewStore([]byte("secret"))
+	secret, err := cookieSecret(cfg.CookieSecret)
+	if err != nil {
+		log.Fatalf("Erro ao gerar segredo do cookie: %v", err)
+	}
+	store := cookie.NewStore(secret)
+	store.Options(sessions.Options{Path: "/", MaxAge: 30 * 24 * 3600, HttpOnly: true, SameSite: http.SameSiteLaxMode})
 	router.Use(sessions.Sessions("whatsapp-session", store))
 
 	// Configurar carregamento de templates - CORREÇÃO DEFINITIVA
@@ -81,6 +153,10 @@ func main() {
 	// Servir arquivos estáticos
 	router.Static("/assets", "./web/assets")
 
+	// Formulário de chave de API para o navegador
+	router.GET("/login", authHandler.LoginPage)
+	router.POST("/login", authHandler.Login)
+
 	// Grupo de rotas principais
 	mainRoutes := router.Group("/")
 	mainRoutes.Use(authHandler.AuthMiddleware())
@@ -95,12 +171,63 @@ func main() {
 	{
 		sessionRoutes.GET("/", sessionHandler.GetSessionsHTML)
 		sessionRoutes.GET("/list", sessionHandler.GetSessions)
+		sessionRoutes.GET("/tags", sessionHandler.GetTags)
+		sessionRoutes.POST("/pair-code", sessionHandler.GeneratePairCode)
+		sessionRoutes.POST("/import", sessionHandler.ImportSession)
 		sessionRoutes.GET("/:id", sessionHandler.GetSessionInfo)
+		sessionRoutes.PATCH("/:id", sessionHandler.UpdateSession)
+		sessionRoutes.PUT("/:id/proxy", sessionHandler.SetSessionProxy)
+		sessionRoutes.PUT("/:id/rate-limits", sessionHandler.SetSessionRateLimits)
+		sessionRoutes.DELETE("/:id/rate-limits", sessionHandler.ResetSessionRateLimits)
 		sessionRoutes.DELETE("/:id", sessionHandler.DeleteSession)
+		sessionRoutes.GET("/:id/health", sessionHandler.GetSessionHealth)
+		sessionRoutes.GET("/:id/export", sessionHandler.ExportSession)
 		sessionRoutes.POST("/:id/disconnect", whatsappHandler.DisconnectSession)
 		// Adicionar rotas para envio de mensagens
 		sessionRoutes.GET("/:id/message", whatsappHandler.GetMessageForm)
 		sessionRoutes.POST("/:id/message", whatsappHandler.SendMessage)
+		sessionRoutes.POST("/:id/media", whatsappHandler.SendMedia)
+		sessionRoutes.GET("/:id/outbox", whatsappHandler.ListOutbox)
+		sessionRoutes.GET("/:id/outbox/:message_id", whatsappHandler.GetOutboxMessage)
+		sessionRoutes.POST("/:id/outbox/:message_id/retry", whatsappHandler.RetryOutboxMessage)
+		sessionRoutes.GET("/:id/schedules", whatsappHandler.ListSchedules)
+		sessionRoutes.POST("/:id/schedules", whatsappHandler.CreateSchedule)
+		sessionRoutes.GET("/:id/schedules/:schedule_id", whatsappHandler.GetSchedule)
+		sessionRoutes.DELETE("/:id/schedules/:schedule_id", whatsappHandler.CancelSchedule)
+		sessionRoutes.GET("/:id/chats", whatsappHandler.ListChats)
+		sessionRoutes.GET("/:id/chats/:jid/messages", whatsappHandler.ListChatMessages)
+	}
+
+	// Grupo de rotas para campanhas de envio em massa
+	campaignRoutes := router.Group("/campaigns")
+	campaignRoutes.Use(authHandler.AuthMiddleware())
+	{
+		campaignRoutes.GET("/", whatsappHandler.GetCampaignsHTML)
+		campaignRoutes.GET("/list", whatsappHandler.ListCampaigns)
+		campaignRoutes.POST("", whatsappHandler.CreateCampaign)
+		campaignRoutes.GET("/:id", whatsappHandler.GetCampaign)
+		campaignRoutes.GET("/:id/recipients", whatsappHandler.ListCampaignRecipients)
+		campaignRoutes.POST("/:id/pause", whatsappHandler.PauseCampaign)
+		campaignRoutes.POST("/:id/resume", whatsappHandler.ResumeCampaign)
+		campaignRoutes.POST("/:id/cancel", whatsappHandler.CancelCampaign)
+	}
+
+	// Grupo de rotas para modelos de mensagem
+	templateRoutes := router.Group("/templates")
+	templateRoutes.Use(authHandler.AuthMiddleware())
+	{
+		templateRoutes.GET("", whatsappHandler.ListTemplates)
+		templateRoutes.POST("", whatsappHandler.CreateTemplate)
+		templateRoutes.GET("/:id", whatsappHandler.GetTemplate)
+		templateRoutes.PUT("/:id", whatsappHandler.UpdateTemplate)
+		templateRoutes.DELETE("/:id", whatsappHandler.DeleteTemplate)
+	}
+
+	// Estado de entrega das mensagens enviadas
+	messageRoutes := router.Group("/messages")
+	messageRoutes.Use(authHandler.AuthMiddleware())
+	{
+		messageRoutes.GET("/:id", whatsappHandler.GetMessage)
 	}
 
 	// Grupo de rotas para QR Code
@@ -116,10 +243,11 @@ func main() {
 			c.Header("Expires", "0")
 			sessionHandler.GenerateQRCodeRaw(c)
 		})
+		qrRoutes.GET("/stream", sessionHandler.StreamQRCode)
 	}
 
 	// Rotas de status
-	router.GET("/connection-status", func(c *gin.Context) {
+	router.GET("/connection-status", authHandler.AuthMiddleware(), func(c *gin.Context) {
 		// Adicionar cabeçalhos para prevenir caching
 		c.Header("Cache-Control", "no-store, no-cache, must-revalidate")
 		c.Header("Pragma", "no-cache")
@@ -127,14 +255,72 @@ func main() {
 		sessionHandler.CheckConnection(c)
 	})
 
+	// Rotas do reconciliador, restritas ao workspace padrão (operador)
+	reconcileRoutes := router.Group("/reconcile")
+	reconcileRoutes.Use(authHandler.AuthMiddleware())
+	{
+		reconcileRoutes.POST("", sessionHandler.RunReconcile)
+		reconcileRoutes.GET("/audit", sessionHandler.GetReconcileAudit)
+	}
+
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
 	// Iniciar servidor
 	addr := fmt.Sprintf(":%s", cfg.Port)
-	log.Printf("Servidor iniciado em http://localhost%s", addr)
-	if err := router.Run(addr); err != nil {
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: router,
+	}
+
+	// Streams de longa duração (QR Code via SSE) usam o contexto da requisição,
+	// que é cancelado assim que o desligamento começa
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	srv.BaseContext = func(net.Listener) context.Context { return baseCtx }
+	srv.RegisterOnShutdown(cancelBase)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Servidor iniciado em http://localhost%s", addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serverErr <- err
+		}
+	}()
+
+	stop, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	select {
+	case err := <-serverErr:
 		log.Fatalf("Erro ao iniciar servidor: %v", err)
+	case <-stop.Done():
 	}
+	stopSignals()
+
+	log.Printf("[Shutdown] Sinal recebido, desligando (prazo de %s)", cfg.ShutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// 1. Parar de aceitar requisições; as em andamento terminam em paralelo
+	httpDone := make(chan error, 1)
+	go func() {
+		httpDone <- srv.Shutdown(ctx)
+	}()
+
+	// 2-4. Drenar envios (inclusive os das requisições abertas), desconectar os
+	// clientes e gravar as estatísticas
+	summary := waManager.Shutdown(ctx)
+
+	if err := <-httpDone; err != nil {
+		log.Printf("[Shutdown] Requisições ainda abertas no fim do prazo, encerrando à força: %v", err)
+		srv.Close()
+	}
+
+	// 5. Fechar o banco do painel
+	if err := db.Close(); err != nil {
+		log.Printf("[Shutdown] Erro ao fechar banco de dados: %v", err)
+	}
+
+	log.Printf("[Shutdown] Concluído: %s", summary)
 }
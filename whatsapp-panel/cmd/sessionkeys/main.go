@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"whatsapp-panel/internal/config"
+	"whatsapp-panel/internal/services/whatsapp"
+)
+
+const usage = `Uso: sessionkeys <comando> [opções]
+
+Comandos:
+  generate                              gera uma nova chave de sessão
+  status                                mostra a chave de cada arquivo de sessão
+  rotate -new-key CHAVE | -new-key-file ARQUIVO | -decrypt
+                                        regrava os arquivos de sessão com a nova
+                                        chave (ou sem criptografia, com -decrypt)
+
+A chave atual vem de SESSION_KEY ou SESSION_KEY_FILE. Pare o servidor antes de
+usar rotate e, depois, configure a nova chave antes de iniciá-lo novamente.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	newKeyValue := flags.String("new-key", "", "nova chave (hexadecimal ou base64)")
+	newKeyFile := flags.String("new-key-file", "", "arquivo com a nova chave")
+	decrypt := flags.Bool("decrypt", false, "remover a criptografia dos arquivos")
+	flags.Parse(os.Args[2:])
+
+	if os.Args[1] == "generate" {
+		key, err := whatsapp.GenerateStoreKey()
+		if err != nil {
+			fmt.Println("Erro ao gerar chave:", err)
+			os.Exit(1)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	// Usar o mesmo diretório de sessões e a mesma chave do servidor
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Println("Erro ao carregar configurações:", err)
+		os.Exit(1)
+	}
+
+	sessions, err := whatsapp.NewSessionStore(cfg.SessionsDir)
+	if err != nil {
+		fmt.Println("Erro ao abrir diretório de sessões:", err)
+		os.Exit(1)
+	}
+	sessions.Key, err = whatsapp.LoadStoreKey(cfg.SessionKey, cfg.SessionKeyFile)
+	if err != nil {
+		fmt.Println("Erro ao carregar chave de sessão:", err)
+		os.Exit(1)
+	}
+
+	files, err := sessions.Files()
+	if err != nil {
+		fmt.Println("Erro ao listar sessões:", err)
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "status":
+		if sessions.Key != nil {
+			fmt.Printf("Chave configurada: %s\n", sessions.Key.ID())
+		} else {
+			fmt.Println("Nenhuma chave configurada")
+		}
+		for _, file := range files {
+			id := sessions.SessionID(file)
+			keyID, err := sessions.KeyID(id)
+			switch {
+			case err != nil:
+				fmt.Printf("%s\terro: %v\n", id, err)
+			case keyID == "":
+				fmt.Printf("%s\tsem criptografia\n", id)
+			default:
+				fmt.Printf("%s\tchave %s\n", id, keyID)
+			}
+		}
+
+	case "rotate":
+		var newKey *whatsapp.StoreKey
+		if !*decrypt {
+			newKey, err = whatsapp.LoadStoreKey(*newKeyValue, *newKeyFile)
+			if err != nil {
+				fmt.Println("Erro ao carregar nova chave:", err)
+				os.Exit(1)
+			}
+			if newKey == nil {
+				fmt.Println("Informe -new-key, -new-key-file ou -decrypt")
+				os.Exit(1)
+			}
+		}
+
+		// Arquivos já gravados com a nova chave são mantidos, então uma
+		// rotação interrompida pode ser repetida
+		failed := 0
+		for _, file := range files {
+			id := sessions.SessionID(file)
+			if err := sessions.Rekey(id, newKey); err != nil {
+				fmt.Printf("%s\terro: %v\n", id, err)
+				failed++
+				continue
+			}
+			fmt.Printf("%s\tok\n", id)
+		}
+		if failed > 0 {
+			fmt.Printf("%d de %d sessões não foram regravadas\n", failed, len(files))
+			os.Exit(1)
+		}
+		if newKey != nil {
+			fmt.Printf("%d sessões regravadas com a chave %s; configure-a em SESSION_KEY ou SESSION_KEY_FILE\n", len(files), newKey.ID())
+		} else {
+			fmt.Printf("%d sessões regravadas sem criptografia; remova SESSION_KEY e SESSION_KEY_FILE\n", len(files))
+		}
+
+	default:
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"whatsapp-panel/internal/config"
+	"whatsapp-panel/internal/storage"
+)
+
+const usage = `Uso: tenants <comando> [opções]
+
+Comandos:
+  list                                  lista os workspaces
+  create -id ID -name NOME [-max-sessions N]
+                                        cria um workspace e mostra sua chave de API
+  quota -id ID -max-sessions N          altera o limite de sessões (0 = sem limite)
+  rotate-key -id ID                     gera uma nova chave de API
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	id := flags.String("id", "", "ID do workspace (ex: acme)")
+	name := flags.String("name", "", "nome do workspace")
+	maxSessions := flags.Int("max-sessions", 0, "número máximo de sessões (0 = sem limite)")
+	flags.Parse(os.Args[2:])
+
+	// Usar o mesmo banco do servidor
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Println("Erro ao carregar configurações:", err)
+		os.Exit(1)
+	}
+
+	db, err := storage.NewDatabase(cfg.DatabasePath)
+	if err != nil {
+		fmt.Println("Erro ao abrir banco de dados:", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	switch os.Args[1] {
+	case "list":
+		tenants, err := db.ListTenants()
+		if err != nil {
+			fmt.Println("Erro ao listar workspaces:", err)
+			os.Exit(1)
+		}
+		for _, t := range tenants {
+			limit := "sem limite"
+			if t.MaxSessions > 0 {
+				limit = fmt.Sprintf("%d sessões", t.MaxSessions)
+			}
+			fmt.Printf("%s\t%s\t%s\n", t.ID, t.Name, limit)
+		}
+
+	case "create":
+		if *id == "" || *name == "" {
+			fmt.Println("Informe -id e -name")
+			os.Exit(1)
+		}
+		tenant, err := db.CreateTenant(*id, *name, *maxSessions)
+		if err != nil {
+			fmt.Println("Erro ao criar workspace:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Workspace %s criado.\nChave de API (guarde, não será mostrada novamente): %s\n", tenant.ID, tenant.APIKey)
+
+	case "quota":
+		if *id == "" {
+			fmt.Println("Informe -id")
+			os.Exit(1)
+		}
+		if err := db.SetTenantQuota(*id, *maxSessions); err != nil {
+			fmt.Println("Erro ao alterar limite:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Limite do workspace %s alterado para %d sessões\n", *id, *maxSessions)
+
+	case "rotate-key":
+		if *id == "" {
+			fmt.Println("Informe -id")
+			os.Exit(1)
+		}
+		apiKey, err := db.RotateTenantKey(*id)
+		if err != nil {
+			fmt.Println("Erro ao gerar chave:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Nova chave de API do workspace %s: %s\n", *id, apiKey)
+
+	default:
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+}
@@ -10,15 +10,23 @@ import (
 	"os"
 	"time"
 
+	"whatsapp-panel/internal/config"
+	"whatsapp-panel/internal/models"
 	"whatsapp-panel/internal/services/whatsapp"
 	"whatsapp-panel/internal/storage"
 
 	qrterminal "github.com/mdp/qrterminal/v3"
-	"go.mau.fi/whatsmeow/store/sqlstore"
 	"go.mau.fi/whatsmeow/types"
 )
 
 func main() {
+	// Carregar configurações (diretório de sessões compartilhado com o servidor)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Println("Erro ao carregar configurações:", err)
+		os.Exit(1)
+	}
+
 	// Inicializar banco de dados (usa armazenamento existente)
 	db, err := storage.NewDatabase("contacts.db")
 	if err != nil {
@@ -28,8 +36,12 @@ func main() {
 	defer db.Close()
 
 	// Criar gerenciador e cliente WhatsApp
-	mgr := whatsapp.NewManager(db)
-	client, err := mgr.NewClient()
+	mgr, err := whatsapp.NewManager(cfg, db)
+	if err != nil {
+		fmt.Println("Erro ao criar gerenciador WhatsApp:", err)
+		os.Exit(1)
+	}
+	client, err := mgr.NewClient(models.DefaultTenantID)
 	if err != nil {
 		fmt.Println("Erro ao criar cliente WhatsApp:", err)
 		os.Exit(1)
@@ -76,14 +88,17 @@ func main() {
 		time.Sleep(500 * time.Millisecond)
 	}
 
-	// Coletar contatos via SQLStore
-	devid := *client.WAClient.Store.ID
-	sqlClient := sqlstore.NewSQLStore(client.Store, devid)
-	contactsMap, err := sqlClient.GetAllContacts()
+	// Coletar contatos do store do dispositivo
+	contactsMap, err := client.WAClient.GetAllContacts()
 	if err != nil {
 		fmt.Println("Erro ao obter contatos:", err)
 		os.Exit(1)
 	}
+	// Gravar o store da sessão pareada (cifrado, se houver chave)
+	if err := mgr.Sessions.Close(client.ID, client.Store); err != nil {
+		fmt.Println("Erro ao gravar sessão:", err)
+		os.Exit(1)
+	}
 	// Preparar progresso de exportação
 	total := len(contactsMap)
 	sliceJIDs := make([]types.JID, 0, total)
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,7 +14,59 @@ type Config struct {
 	Port         string
 	DatabasePath string
 	StoreDir     string
-	Debug        bool
+	SessionsDir  string // um banco whatsmeow por sessão (padrão: STORE_DIR/sessions)
+	// Chave de criptografia dos arquivos de sessão (vazia = sem criptografia)
+	SessionKey     string
+	SessionKeyFile string
+	Debug          bool
+	// AdminAPIKey dá acesso de operador (rotas do servidor inteiro, ex:
+	// /reconcile) e ao workspace padrão; vazia desativa as rotas de operador
+	AdminAPIKey string
+	// CookieSecret assina o cookie de sessão do navegador; vazia gera um
+	// segredo aleatório a cada início
+	CookieSecret string
+	// FakeWhatsApp substitui o whatsmeow por um transporte em memória (modo offline)
+	FakeWhatsApp bool
+
+	// Reconexão automática com backoff exponencial e jitter
+	ReconnectInitialDelay time.Duration
+	ReconnectMaxDelay     time.Duration
+	ReconnectMultiplier   float64
+	ReconnectJitter       float64
+	ReconnectMaxAttempts  int
+
+	// Watchdog que detecta sessões zumbis e força a reconexão
+	WatchdogInterval         time.Duration
+	WatchdogInboundTimeout   time.Duration
+	WatchdogKeepAliveMaxFail time.Duration
+
+	// Reconciliador de sessões órfãs (substitui a limpeza por cron)
+	ReconcileInterval time.Duration
+	ReconcileGrace    time.Duration
+	ReconcileDryRun   bool
+
+	// Envio de mídias pela API
+	MediaMaxSize          int64
+	MediaFetchTimeout     time.Duration
+	MediaAllowPrivateURLs bool
+
+	// Fila de envio durável
+	OutboxMaxAttempts   int
+	OutboxRetryDelay    time.Duration
+	OutboxRetryMaxDelay time.Duration
+
+	// Limites de envio por sessão (0 desativa), para reduzir o risco de banimento
+	RateLimitPerMinute int
+	RateLimitPerHour   int
+	RateLimitPerDay    int
+	SendDelayMin       time.Duration
+	SendDelayMax       time.Duration
+
+	// Campanhas de envio em massa
+	CampaignMaxRecipients int
+
+	// ShutdownTimeout limita a espera por requisições e envios em andamento ao desligar
+	ShutdownTimeout time.Duration
 }
 
 // LoadConfig carrega as configurações do ambiente
@@ -43,6 +97,12 @@ func LoadConfig() (*Config, error) {
 		dbPath = filepath.Join(storeDir, "whatsapp.db")
 	}
 
+	// Configurar diretório dos arquivos de sessão do WhatsApp
+	sessionsDir := os.Getenv("SESSIONS_DIR")
+	if sessionsDir == "" {
+		sessionsDir = filepath.Join(storeDir, "sessions")
+	}
+
 	// Obter porta do servidor
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -52,10 +112,77 @@ func LoadConfig() (*Config, error) {
 	// Verificar modo debug
 	debug := os.Getenv("DEBUG") == "true"
 
+	// Verificar modo offline (sem conexão real com o WhatsApp)
+	fakeWhatsApp := os.Getenv("FAKE_WHATSAPP") == "true"
+
 	return &Config{
 		Port:         port,
 		DatabasePath: dbPath,
 		StoreDir:     storeDir,
+		SessionsDir:  sessionsDir,
 		Debug:        debug,
+		FakeWhatsApp: fakeWhatsApp,
+
+		AdminAPIKey:  os.Getenv("ADMIN_API_KEY"),
+		CookieSecret: os.Getenv("COOKIE_SECRET"),
+
+		SessionKey:     os.Getenv("SESSION_KEY"),
+		SessionKeyFile: os.Getenv("SESSION_KEY_FILE"),
+
+		ReconnectInitialDelay: getEnvDuration("RECONNECT_INITIAL_DELAY", 2*time.Second),
+		ReconnectMaxDelay:     getEnvDuration("RECONNECT_MAX_DELAY", 5*time.Minute),
+		ReconnectMultiplier:   getEnvFloat("RECONNECT_MULTIPLIER", 2),
+		ReconnectJitter:       getEnvFloat("RECONNECT_JITTER", 0.2),
+		ReconnectMaxAttempts:  getEnvInt("RECONNECT_MAX_ATTEMPTS", 10),
+
+		WatchdogInterval:         getEnvDuration("WATCHDOG_INTERVAL", 30*time.Second),
+		WatchdogInboundTimeout:   getEnvDuration("WATCHDOG_INBOUND_TIMEOUT", 30*time.Minute),
+		WatchdogKeepAliveMaxFail: getEnvDuration("WATCHDOG_KEEPALIVE_MAX_FAIL", 3*time.Minute),
+
+		ReconcileInterval: getEnvDuration("RECONCILE_INTERVAL", 10*time.Minute),
+		ReconcileGrace:    getEnvDuration("RECONCILE_GRACE", 10*time.Minute),
+		ReconcileDryRun:   os.Getenv("RECONCILE_DRY_RUN") == "true",
+
+		MediaMaxSize:          int64(getEnvInt("MEDIA_MAX_SIZE_MB", 100)) << 20,
+		MediaFetchTimeout:     getEnvDuration("MEDIA_FETCH_TIMEOUT", 60*time.Second),
+		MediaAllowPrivateURLs: os.Getenv("MEDIA_ALLOW_PRIVATE_URLS") == "true",
+
+		OutboxMaxAttempts:   getEnvInt("OUTBOX_MAX_ATTEMPTS", 5),
+		OutboxRetryDelay:    getEnvDuration("OUTBOX_RETRY_DELAY", 10*time.Second),
+		OutboxRetryMaxDelay: getEnvDuration("OUTBOX_RETRY_MAX_DELAY", 10*time.Minute),
+
+		RateLimitPerMinute: getEnvInt("RATE_LIMIT_PER_MINUTE", 20),
+		RateLimitPerHour:   getEnvInt("RATE_LIMIT_PER_HOUR", 300),
+		RateLimitPerDay:    getEnvInt("RATE_LIMIT_PER_DAY", 1000),
+		SendDelayMin:       getEnvDuration("SEND_DELAY_MIN", time.Second),
+		SendDelayMax:       getEnvDuration("SEND_DELAY_MAX", 3*time.Second),
+
+		CampaignMaxRecipients: getEnvInt("CAMPAIGN_MAX_RECIPIENTS", 10000),
+
+		ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
 	}, nil
 }
+
+// getEnvDuration lê uma duração (ex: "30s", "5m") do ambiente, usando o padrão se ausente ou inválida
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return v
+	}
+	return def
+}
+
+// getEnvFloat lê um número decimal do ambiente, usando o padrão se ausente ou inválido
+func getEnvFloat(key string, def float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return v
+	}
+	return def
+}
+
+// getEnvInt lê um inteiro do ambiente, usando o padrão se ausente ou inválido
+func getEnvInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return def
+}
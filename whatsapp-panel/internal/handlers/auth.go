@@ -1,17 +1,171 @@
 package handlers
 
-import "github.com/gin-gonic/gin"
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
 
-type AuthHandler struct{}
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
 
-func NewAuthHandler() *AuthHandler {
-	return &AuthHandler{}
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/storage"
+)
+
+const (
+	// tenantKey é a chave do tenant da requisição no gin.Context
+	tenantKey = "tenant"
+	// operatorKey marca no gin.Context as requisições feitas com a chave de administrador
+	operatorKey = "operator"
+)
+
+type AuthHandler struct {
+	DB *storage.Database
+	// AdminAPIKey é a chave de operador (ADMIN_API_KEY); vazia, não há operador
+	AdminAPIKey string
+}
+
+func NewAuthHandler(db *storage.Database, adminAPIKey string) *AuthHandler {
+	return &AuthHandler{DB: db, AdminAPIKey: adminAPIKey}
 }
 
-// Middleware básico de autenticação - pode ser expandido no futuro
+// AuthMiddleware identifica o workspace (tenant) da requisição pela chave de
+// API, enviada no cabeçalho X-API-Key ou uma vez via ?api_key= (guardada no
+// cookie de sessão para o navegador). Requisições sem chave são recusadas;
+// páginas abertas no navegador são redirecionadas para /login. A chave de
+// administrador acessa o workspace padrão com papel de operador.
 func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Por enquanto, permitir todo acesso
+		session := sessions.Default(c)
+		apiKey := c.GetHeader("X-API-Key")
+		fromQuery, fromCookie := false, false
+		if apiKey == "" {
+			if key := c.Query("api_key"); key != "" {
+				apiKey = key
+				fromQuery = true
+			} else if key, ok := session.Get("api_key").(string); ok {
+				apiKey = key
+				fromCookie = true
+			}
+		}
+
+		if apiKey == "" {
+			unauthorized(c, "Chave de API não informada")
+			return
+		}
+
+		tenant, operator, err := h.lookupKey(apiKey)
+		if err == storage.ErrTenantNotFound {
+			// Chave revogada: esquecer a do cookie para não bloquear o navegador
+			if fromCookie {
+				session.Delete("api_key")
+				session.Save()
+			}
+			unauthorized(c, "Chave de API inválida")
+			return
+		}
+		if err != nil {
+			log.Printf("[Auth] Erro ao carregar workspace: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Erro ao carregar workspace"})
+			return
+		}
+
+		if fromQuery {
+			session.Set("api_key", apiKey)
+			if err := session.Save(); err != nil {
+				log.Printf("[Auth] Erro ao salvar chave na sessão: %v", err)
+			}
+		}
+
+		c.Set(tenantKey, tenant)
+		c.Set(operatorKey, operator)
 		c.Next()
 	}
 }
+
+// LoginPage mostra o formulário de chave de API para quem abre o painel no
+// navegador sem chave
+func (h *AuthHandler) LoginPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "login.html", gin.H{"Next": safeNext(c.Query("next"))})
+}
+
+// Login valida a chave informada no formulário, guarda-a no cookie de sessão
+// e volta para a página pedida
+func (h *AuthHandler) Login(c *gin.Context) {
+	apiKey := strings.TrimSpace(c.PostForm("api_key"))
+	next := safeNext(c.PostForm("next"))
+
+	if apiKey == "" {
+		c.HTML(http.StatusUnauthorized, "login.html", gin.H{"Next": next, "Error": "Chave de API não informada"})
+		return
+	}
+	if _, _, err := h.lookupKey(apiKey); err != nil {
+		if err != storage.ErrTenantNotFound {
+			log.Printf("[Auth] Erro ao carregar workspace: %v", err)
+		}
+		c.HTML(http.StatusUnauthorized, "login.html", gin.H{"Next": next, "Error": "Chave de API inválida"})
+		return
+	}
+
+	session := sessions.Default(c)
+	session.Set("api_key", apiKey)
+	if err := session.Save(); err != nil {
+		log.Printf("[Auth] Erro ao salvar chave na sessão: %v", err)
+		c.HTML(http.StatusInternalServerError, "login.html", gin.H{"Next": next, "Error": "Erro ao salvar a sessão"})
+		return
+	}
+	c.Redirect(http.StatusSeeOther, next)
+}
+
+// lookupKey retorna o workspace da chave e se ela é a de administrador
+func (h *AuthHandler) lookupKey(apiKey string) (*models.Tenant, bool, error) {
+	if h.isAdminKey(apiKey) {
+		tenant, err := h.DB.GetTenant(models.DefaultTenantID)
+		return tenant, true, err
+	}
+	tenant, err := h.DB.GetTenantByAPIKey(apiKey)
+	return tenant, false, err
+}
+
+// unauthorized recusa a requisição sem chave válida: páginas abertas no
+// navegador vão para /login, o restante recebe 401 em JSON
+func unauthorized(c *gin.Context, message string) {
+	if c.GetHeader("HX-Request") == "true" {
+		// Requisições htmx trocariam só um trecho da página pelo formulário
+		c.Header("HX-Redirect", "/login")
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if c.Request.Method == http.MethodGet && strings.Contains(c.GetHeader("Accept"), "text/html") {
+		// A chave recusada não volta na URL de retorno
+		next := *c.Request.URL
+		query := next.Query()
+		query.Del("api_key")
+		next.RawQuery = query.Encode()
+
+		c.Redirect(http.StatusFound, "/login?next="+url.QueryEscape(next.RequestURI()))
+		c.Abort()
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
+
+// safeNext aceita como retorno do login apenas caminhos do próprio painel
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// isAdminKey compara a chave com ADMIN_API_KEY em tempo constante
+func (h *AuthHandler) isAdminKey(apiKey string) bool {
+	return h.AdminAPIKey != "" && subtle.ConstantTimeCompare([]byte(apiKey), []byte(h.AdminAPIKey)) == 1
+}
+
+// currentTenant retorna o workspace identificado pelo AuthMiddleware
+func currentTenant(c *gin.Context) *models.Tenant {
+	return c.MustGet(tenantKey).(*models.Tenant)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsapp-panel/internal/services/whatsapp"
+	"whatsapp-panel/internal/storage"
+)
+
+// bundlePasswordHeader carrega a senha do pacote sem expô-la na URL
+const bundlePasswordHeader = "X-Bundle-Password"
+
+// ExportSession baixa o pacote portátil da sessão (banco do dispositivo,
+// dados, notas e tags), cifrado se o cabeçalho X-Bundle-Password for enviado
+func (h *SessionHandler) ExportSession(c *gin.Context) {
+	sessionID := c.Param("id")
+
+	var bundle bytes.Buffer
+	err := h.WAClientManager.ExportSession(currentTenant(c).ID, sessionID, c.GetHeader(bundlePasswordHeader), &bundle)
+	if err == storage.ErrSessionNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
+		return
+	}
+	if err != nil {
+		log.Printf("[Bundle] Erro ao exportar sessão %s: %v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao exportar sessão: " + err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="session-%s.wabundle"`, sessionID))
+	c.Data(http.StatusOK, "application/octet-stream", bundle.Bytes())
+}
+
+// ImportSession importa um pacote gerado por ExportSession, enviado como
+// multipart no campo "bundle". A senha vai no campo "password" ou no
+// cabeçalho X-Bundle-Password.
+func (h *SessionHandler) ImportSession(c *gin.Context) {
+	file, err := c.FormFile("bundle")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Envie o pacote no campo \"bundle\""})
+		return
+	}
+	if file.Size > whatsapp.MaxBundleSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Pacote muito grande"})
+		return
+	}
+
+	password := c.PostForm("password")
+	if password == "" {
+		password = c.GetHeader(bundlePasswordHeader)
+	}
+
+	bundle, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao ler pacote"})
+		return
+	}
+	defer bundle.Close()
+
+	client, err := h.WAClientManager.ImportSession(currentTenant(c).ID, bundle, password)
+	if err != nil {
+		log.Printf("[Bundle] Erro ao importar pacote: %v", err)
+		status, message := importError(err)
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"session_id": client.ID,
+		"status":     client.Status(),
+	})
+}
+
+// importError traduz um erro de Manager.ImportSession em status HTTP e mensagem
+func importError(err error) (int, string) {
+	switch {
+	case errors.Is(err, whatsapp.ErrBundlePasswordRequired):
+		return http.StatusUnauthorized, "Pacote protegido por senha"
+	case errors.Is(err, whatsapp.ErrBundleInvalid):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, storage.ErrSessionExists):
+		return http.StatusConflict, "A sessão do pacote já existe neste servidor"
+	}
+	return newClientError(err)
+}
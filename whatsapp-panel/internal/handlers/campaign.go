@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/services/whatsapp"
+	"whatsapp-panel/internal/storage"
+)
+
+const (
+	// campaignMaxCSVSize limita o arquivo de contatos enviado para uma campanha
+	campaignMaxCSVSize = 10 << 20
+	// campaignRecipientsLimit é o padrão (e o máximo) de destinatários por página
+	campaignRecipientsLimit = 500
+)
+
+// GetCampaignsHTML renderiza a página de campanhas
+func (h *WhatsAppHandler) GetCampaignsHTML(c *gin.Context) {
+	tenant := currentTenant(c)
+	sessions, err := h.DB.GetAllSessions(tenant.ID)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"Error": "Erro ao buscar sessões: " + err.Error(),
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "campaigns.html", gin.H{
+		"Sessions": sessions,
+		"Tenant":   tenant,
+	})
+}
+
+// ListCampaigns lista as campanhas do tenant, da mais recente para a mais
+// antiga; ?session_id= filtra por sessão
+func (h *WhatsAppHandler) ListCampaigns(c *gin.Context) {
+	campaigns, err := h.DB.ListCampaigns(currentTenant(c).ID, c.Query("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar campanhas"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"campaigns": campaigns})
+}
+
+// CreateCampaign cria uma campanha a partir de um CSV de contatos (campo
+// multipart "file"), com a sessão, o nome e a mensagem (ou o modelo) nos
+// demais campos
+func (h *WhatsAppHandler) CreateCampaign(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, campaignMaxCSVSize)
+
+	var req struct {
+		SessionID  string `form:"session_id" binding:"required"`
+		Name       string `form:"name"`
+		Message    string `form:"message"`
+		TemplateID string `form:"template_id"`
+		Variables  string `form:"variables"` // objeto JSON com valores padrão das variáveis
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		if campaignUploadTooLarge(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Dados inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if (req.Message == "") == (req.TemplateID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe \"message\" ou \"template_id\""})
+		return
+	}
+	var variables map[string]string
+	if req.Variables != "" {
+		if err := json.Unmarshal([]byte(req.Variables), &variables); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "variables deve ser um objeto JSON com valores de texto",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		if campaignUploadTooLarge(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Envie a lista de contatos (CSV) no campo \"file\""})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao ler arquivo"})
+		return
+	}
+	defer f.Close()
+
+	list, err := whatsapp.ParseContactList(f)
+	if err != nil {
+		campaignError(c, err)
+		return
+	}
+
+	campaign, err := h.WAClientManager.CreateCampaign(currentTenant(c).ID, whatsapp.CampaignRequest{
+		SessionID:  req.SessionID,
+		Name:       req.Name,
+		Message:    req.Message,
+		TemplateID: req.TemplateID,
+		Variables:  variables,
+	}, list)
+	if err != nil {
+		campaignError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, campaign)
+}
+
+// GetCampaign retorna a campanha com a contagem de destinatários por estado
+func (h *WhatsAppHandler) GetCampaign(c *gin.Context) {
+	campaign, err := h.DB.GetCampaign(currentTenant(c).ID, c.Param("id"))
+	if err != nil {
+		campaignError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, campaign)
+}
+
+// ListCampaignRecipients lista os destinatários da campanha na ordem da
+// lista; aceita ?status=, ?limit= e ?offset=
+func (h *WhatsAppHandler) ListCampaignRecipients(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.RecipientPending, models.RecipientSent, models.RecipientFailed, models.RecipientSkipped:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status inválido (use pending, sent, failed ou skipped)"})
+		return
+	}
+
+	limit, offset, ok := queryPage(c, campaignRecipientsLimit)
+	if !ok {
+		return
+	}
+
+	campaign, err := h.DB.GetCampaign(currentTenant(c).ID, c.Param("id"))
+	if err != nil {
+		campaignError(c, err)
+		return
+	}
+	recipients, err := h.DB.ListCampaignRecipients(campaign.ID, status, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar destinatários"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recipients": recipients})
+}
+
+// PauseCampaign pausa uma campanha em andamento
+func (h *WhatsAppHandler) PauseCampaign(c *gin.Context) {
+	h.campaignAction(c, h.WAClientManager.PauseCampaign)
+}
+
+// ResumeCampaign retoma uma campanha pausada
+func (h *WhatsAppHandler) ResumeCampaign(c *gin.Context) {
+	h.campaignAction(c, h.WAClientManager.ResumeCampaign)
+}
+
+// CancelCampaign cancela uma campanha em andamento ou pausada
+func (h *WhatsAppHandler) CancelCampaign(c *gin.Context) {
+	h.campaignAction(c, h.WAClientManager.CancelCampaign)
+}
+
+func (h *WhatsAppHandler) campaignAction(c *gin.Context, action func(tenantID, campaignID string) (*models.Campaign, error)) {
+	campaign, err := action(currentTenant(c).ID, c.Param("id"))
+	if err != nil {
+		campaignError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, campaign)
+}
+
+// campaignUploadTooLarge responde 413 se o corpo passou de campaignMaxCSVSize
+func campaignUploadTooLarge(c *gin.Context, err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return false
+	}
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("lista de contatos acima de %d MB", campaignMaxCSVSize>>20)})
+	return true
+}
+
+// campaignError responde com o status HTTP correspondente a um erro de campanha
+func campaignError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, storage.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
+	case errors.Is(err, storage.ErrCampaignNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campanha não encontrada"})
+	case errors.Is(err, storage.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Modelo não encontrado"})
+	case errors.Is(err, whatsapp.ErrInvalidCampaign):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrCampaignState):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Erro ao processar campanha",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsapp-panel/internal/services/whatsapp"
+	"whatsapp-panel/internal/storage"
+)
+
+// chatPageLimit é o padrão (e o máximo) de conversas ou mensagens por página
+const chatPageLimit = 100
+
+// ListChats lista as conversas da sessão, da mais recente para a mais antiga,
+// com a última mensagem de cada uma
+func (h *WhatsAppHandler) ListChats(c *gin.Context) {
+	limit, offset, ok := queryPage(c, chatPageLimit)
+	if !ok {
+		return
+	}
+	chats, err := h.WAClientManager.ListChats(currentTenant(c).ID, c.Param("id"), limit, offset)
+	if err != nil {
+		chatError(c, err, "Erro ao listar conversas")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"chats": chats})
+}
+
+// ListChatMessages lista as mensagens de uma conversa da sessão, da mais
+// recente para a mais antiga
+func (h *WhatsAppHandler) ListChatMessages(c *gin.Context) {
+	limit, offset, ok := queryPage(c, chatPageLimit)
+	if !ok {
+		return
+	}
+	messages, err := h.WAClientManager.ListChatMessages(currentTenant(c).ID, c.Param("id"), c.Param("jid"), limit, offset)
+	if err != nil {
+		chatError(c, err, "Erro ao listar mensagens")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+// chatError traduz os erros do histórico de conversas em respostas HTTP
+func chatError(c *gin.Context, err error, message string) {
+	switch {
+	case err == storage.ErrSessionNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
+	case errors.Is(err, whatsapp.ErrInvalidChat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   message,
+			"details": err.Error(),
+		})
+	}
+}
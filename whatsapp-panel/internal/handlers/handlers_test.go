@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+
+	"whatsapp-panel/internal/config"
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/services/whatsapp"
+	"whatsapp-panel/internal/storage"
+)
+
+const testAdminKey = "chave-de-teste"
+
+// newTestRouter monta as rotas da API sobre um Manager com transporte
+// simulado, que pareia sozinho logo após a conexão
+func newTestRouter(t *testing.T) (*gin.Engine, *whatsapp.Manager) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	db, err := storage.NewDatabase(filepath.Join(dir, "panel.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	m, err := whatsapp.NewManager(&config.Config{
+		SessionsDir:         filepath.Join(dir, "sessions"),
+		ReconnectMultiplier: 2,
+	}, db)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	m.NewTransport = whatsapp.FakeTransportFactory(10 * time.Millisecond)
+	if err := m.StartOutbox(); err != nil {
+		t.Fatalf("StartOutbox: %v", err)
+	}
+	t.Cleanup(func() {
+		m.Shutdown(context.Background())
+		db.Close()
+	})
+
+	auth := NewAuthHandler(db, testAdminKey)
+	sessionHandler := NewSessionHandler(m, db)
+	whatsappHandler := NewWhatsAppHandler(m, db)
+
+	router := gin.New()
+	router.Use(sessions.Sessions("whatsapp-session", cookie.NewStore([]byte("segredo-de-teste"))))
+	api := router.Group("/")
+	api.Use(auth.AuthMiddleware())
+	{
+		api.GET("/connection-status", sessionHandler.CheckConnection)
+		api.GET("/sessions/", sessionHandler.GetSessionsHTML)
+		api.GET("/sessions/list", sessionHandler.GetSessions)
+		api.POST("/sessions/pair-code", sessionHandler.GeneratePairCode)
+		api.GET("/sessions/:id", sessionHandler.GetSessionInfo)
+		api.POST("/sessions/:id/message", whatsappHandler.SendMessage)
+	}
+	return router, m
+}
+
+// doRequest executa a requisição com a chave de administrador, se key não
+// for vazia, e retorna a resposta gravada
+func doRequest(router *gin.Engine, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// decodeJSON lê o corpo JSON da resposta, falhando o teste se for inválido
+func decodeJSON(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("resposta não é JSON (%d): %s", w.Code, w.Body.String())
+	}
+	return body
+}
+
+// waitFor aguarda cond ser verdadeira, falhando o teste após o prazo
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("tempo esgotado aguardando %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// pairTestSession cria uma sessão pelo código de pareamento e aguarda o
+// pareamento automático do transporte simulado
+func pairTestSession(t *testing.T, router *gin.Engine) string {
+	t.Helper()
+	w := doRequest(router, http.MethodPost, "/sessions/pair-code", testAdminKey, `{"phone_number": "+55 11 99999-0000"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /sessions/pair-code = %d: %s", w.Code, w.Body.String())
+	}
+	body := decodeJSON(t, w)
+	sessionID, _ := body["SessionID"].(string)
+	if sessionID == "" || body["PairCode"] == "" {
+		t.Fatalf("resposta sem SessionID ou PairCode: %v", body)
+	}
+
+	waitFor(t, "pareamento da sessão", func() bool {
+		w := doRequest(router, http.MethodGet, "/connection-status?session_id="+sessionID, testAdminKey, "")
+		return w.Code == http.StatusOK && decodeJSON(t, w)["connected"] == true
+	})
+	return sessionID
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	tests := []struct {
+		name, key string
+		want      int
+	}{
+		{"sem chave", "", http.StatusUnauthorized},
+		{"chave inválida", "outra", http.StatusUnauthorized},
+		{"chave de administrador", testAdminKey, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := doRequest(router, http.MethodGet, "/sessions/list", tt.key, "")
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, quer %d", tt.name, w.Code, tt.want)
+		}
+		if tt.want == http.StatusUnauthorized && decodeJSON(t, w)["error"] == nil {
+			t.Errorf("%s: resposta sem error: %s", tt.name, w.Body.String())
+		}
+	}
+
+	// O navegador sem chave vai para o formulário, sem a chave recusada na volta
+	req := httptest.NewRequest(http.MethodGet, "/sessions/?api_key=outra&tag=vendas", nil)
+	req.Header.Set("Accept", "text/html")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("página sem chave: status = %d, quer %d", w.Code, http.StatusFound)
+	}
+	if got, want := w.Header().Get("Location"), "/login?next=%2Fsessions%2F%3Ftag%3Dvendas"; got != want {
+		t.Errorf("Location = %q, quer %q", got, want)
+	}
+}
+
+func TestPairCodeCreatesSession(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	w := doRequest(router, http.MethodPost, "/sessions/pair-code", testAdminKey, `{"phone_number": "0123"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("número inválido: status = %d, quer %d", w.Code, http.StatusBadRequest)
+	}
+
+	sessionID := pairTestSession(t, router)
+
+	w = doRequest(router, http.MethodGet, "/sessions/"+sessionID, testAdminKey, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /sessions/%s = %d: %s", sessionID, w.Code, w.Body.String())
+	}
+	body := decodeJSON(t, w)
+	if body["Status"] != models.StatusConnected || body["is_connected"] != true {
+		t.Errorf("sessão = status %v, is_connected %v; quer %s, true", body["Status"], body["is_connected"], models.StatusConnected)
+	}
+	if history, _ := body["status_history"].([]interface{}); len(history) == 0 {
+		t.Error("sessão sem histórico de status")
+	}
+
+	w = doRequest(router, http.MethodGet, "/sessions/desconhecida", testAdminKey, "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("sessão desconhecida: status = %d, quer %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestSendMessageQueues(t *testing.T) {
+	router, m := newTestRouter(t)
+	sessionID := pairTestSession(t, router)
+
+	path := "/sessions/" + sessionID + "/message"
+	w := doRequest(router, http.MethodPost, path, testAdminKey, `{"phone_number": "5511987654321", "message": "Olá"}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("POST %s = %d, quer %d: %s", path, w.Code, http.StatusAccepted, w.Body.String())
+	}
+	if id, _ := decodeJSON(t, w)["message_id"].(string); id == "" {
+		t.Errorf("resposta sem message_id: %s", w.Body.String())
+	}
+
+	client, _ := m.GetClient(models.DefaultTenantID, sessionID)
+	transport := client.WAClient.(*whatsapp.FakeTransport)
+	waitFor(t, "entrega pela fila", func() bool {
+		return len(transport.Sent()) == 1
+	})
+
+	tests := []struct {
+		name, path, body string
+		want             int
+	}{
+		{"sem texto", path, `{"phone_number": "5511987654321"}`, http.StatusBadRequest},
+		{"número inválido", path, `{"phone_number": "abc", "message": "Olá"}`, http.StatusBadRequest},
+		{"sessão desconhecida", "/sessions/desconhecida/message", `{"phone_number": "5511987654321", "message": "Olá"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := doRequest(router, http.MethodPost, tt.path, testAdminKey, tt.body); w.Code != tt.want {
+			t.Errorf("%s: status = %d, quer %d: %s", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+}
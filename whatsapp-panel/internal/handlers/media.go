@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsapp-panel/internal/services/whatsapp"
+)
+
+// mediaFormOverhead é a margem do corpo multipart para os campos além do arquivo
+const mediaFormOverhead = 1 << 20
+
+// mediaRequest são os campos do envio de mídia, em multipart ou JSON
+type mediaRequest struct {
+	PhoneNumber string `json:"phone_number" form:"phone_number"`
+	URL         string `json:"url" form:"url"`
+	Caption     string `json:"caption" form:"caption"`
+	FileName    string `json:"filename" form:"filename"`
+	PTT         bool   `json:"ptt" form:"ptt"`
+	AsDocument  bool   `json:"as_document" form:"as_document"`
+}
+
+// SendMedia envia uma imagem, vídeo, áudio ou documento para um número. O
+// arquivo vai no campo multipart "file" ou é baixado do campo "url" (também
+// aceito em JSON); o tipo da mensagem é escolhido pelo conteúdo do arquivo.
+func (h *WhatsAppHandler) SendMedia(c *gin.Context) {
+	sessionID := c.Param("id")
+	maxSize := h.WAClientManager.Media.MaxSize
+
+	isMultipart := strings.HasPrefix(c.ContentType(), "multipart/form-data")
+	if isMultipart {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+mediaFormOverhead)
+		if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+			mediaError(c, fmt.Errorf("%w: %w", whatsapp.ErrInvalidMedia, err))
+			return
+		}
+	}
+
+	var req mediaRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Dados inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if req.PhoneNumber == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe o campo \"phone_number\""})
+		return
+	}
+
+	client, exists := h.WAClientManager.GetClient(currentTenant(c).ID, sessionID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
+		return
+	}
+
+	var media *whatsapp.Media
+	if file, err := c.FormFile("file"); isMultipart && err == nil {
+		if file.Size > maxSize {
+			mediaError(c, whatsapp.ErrMediaTooLarge)
+			return
+		}
+		if media, err = readMediaFile(file); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao ler arquivo"})
+			return
+		}
+	} else if req.URL != "" {
+		if media, err = h.WAClientManager.FetchMedia(c.Request.Context(), req.URL); err != nil {
+			mediaError(c, err)
+			return
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Envie o arquivo no campo \"file\" ou informe \"url\""})
+		return
+	}
+
+	if req.FileName != "" {
+		media.FileName = req.FileName
+	}
+	media.Caption = req.Caption
+	media.PTT = req.PTT
+	media.AsDocument = req.AsDocument
+
+	msg, err := client.SendMediaMessage(req.PhoneNumber, media)
+	if err != nil {
+		mediaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "Mídia enviada com sucesso",
+		"message_id": msg.ID,
+		"status":     msg.Status,
+		"media_type": media.Kind,
+		"mimetype":   media.MimeType,
+	})
+}
+
+// readMediaFile lê um arquivo recebido em multipart
+func readMediaFile(file *multipart.FileHeader) (*whatsapp.Media, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return &whatsapp.Media{Data: data, FileName: file.Filename, MimeType: file.Header.Get("Content-Type")}, nil
+}
+
+// mediaError responde com o status HTTP correspondente a um erro de mídia
+func mediaError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	var rateErr *whatsapp.RateLimitError
+	switch {
+	case errors.As(err, &rateErr):
+		rateLimited(c, rateErr)
+	case errors.As(err, &maxBytesErr):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("%v: arquivo acima de %d MB", whatsapp.ErrMediaTooLarge, (maxBytesErr.Limit-mediaFormOverhead)>>20)})
+	case errors.Is(err, whatsapp.ErrMediaTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrInvalidMedia), errors.Is(err, whatsapp.ErrInvalidPhone):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrNotConnected):
+		c.JSON(http.StatusConflict, gin.H{"error": "Sessão desconectada", "details": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Erro ao enviar mídia",
+			"details": err.Error(),
+		})
+	}
+}
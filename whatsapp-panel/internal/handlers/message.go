@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsapp-panel/internal/storage"
+)
+
+// GetMessage retorna o estado de entrega de uma mensagem enviada, pelo
+// message_id devolvido no envio ou pelo ID do WhatsApp, com os horários de
+// envio, entrega, leitura e reprodução
+func (h *WhatsAppHandler) GetMessage(c *gin.Context) {
+	msg, err := h.WAClientManager.GetMessage(currentTenant(c).ID, c.Param("id"))
+	if err == storage.ErrMessageNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mensagem não encontrada"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Erro ao ler mensagem",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, msg)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/services/whatsapp"
+	"whatsapp-panel/internal/storage"
+)
+
+// outboxListLimit é o padrão (e o máximo) de mensagens retornadas por ListOutbox
+const outboxListLimit = 200
+
+// ListOutbox lista as mensagens da fila de envio da sessão, da mais recente
+// para a mais antiga; aceita ?status=, ?limit= e ?offset=. ?status=dead
+// retorna a dead-letter.
+func (h *WhatsAppHandler) ListOutbox(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.OutboundQueued, models.OutboundSending, models.OutboundSent, models.OutboundDead:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status inválido (use queued, sending, sent ou dead)"})
+		return
+	}
+
+	limit, offset, ok := queryPage(c, outboxListLimit)
+	if !ok {
+		return
+	}
+
+	messages, err := h.DB.ListOutbound(currentTenant(c).ID, c.Param("id"), status, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao ler fila de envio"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+// GetOutboxMessage retorna o estado de uma mensagem da fila
+func (h *WhatsAppHandler) GetOutboxMessage(c *gin.Context) {
+	msg, err := h.DB.GetOutbound(currentTenant(c).ID, c.Param("message_id"))
+	if err == nil && msg.SessionID != c.Param("id") {
+		err = storage.ErrOutboundNotFound
+	}
+	if err == storage.ErrOutboundNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mensagem não encontrada"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao ler mensagem"})
+		return
+	}
+	c.JSON(http.StatusOK, msg)
+}
+
+// RetryOutboxMessage devolve à fila uma mensagem em dead-letter
+func (h *WhatsAppHandler) RetryOutboxMessage(c *gin.Context) {
+	msg, err := h.WAClientManager.RetryOutbound(currentTenant(c).ID, c.Param("id"), c.Param("message_id"))
+	switch {
+	case err == storage.ErrOutboundNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mensagem não encontrada"})
+	case err == whatsapp.ErrOutboundNotDead:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao reenviar mensagem"})
+	default:
+		c.JSON(http.StatusOK, msg)
+	}
+}
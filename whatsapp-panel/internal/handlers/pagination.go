@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryPage lê os parâmetros limit e offset da consulta; limit vale maxLimit
+// se omitido e nunca passa dele. Em caso de erro a resposta já foi enviada.
+func queryPage(c *gin.Context, maxLimit int) (limit, offset int, ok bool) {
+	limit = maxLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit inválido"})
+			return 0, 0, false
+		}
+		limit = min(parsed, maxLimit)
+	}
+	if value := c.Query("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset inválido"})
+			return 0, 0, false
+		}
+		offset = parsed
+	}
+	return limit, offset, true
+}
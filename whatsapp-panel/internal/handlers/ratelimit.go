@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/services/whatsapp"
+	"whatsapp-panel/internal/storage"
+)
+
+// rateLimited responde 429 com o cabeçalho Retry-After
+func rateLimited(c *gin.Context, err *whatsapp.RateLimitError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       err.Error(),
+		"retry_after": math.Ceil(err.RetryAfter.Seconds()),
+	})
+}
+
+// SetSessionRateLimits altera os limites de envio da sessão. Campos omitidos
+// mantêm o valor atual: {"per_minute": 10, "per_day": 500, "min_delay_ms": 2000, "max_delay_ms": 5000}
+func (h *SessionHandler) SetSessionRateLimits(c *gin.Context) {
+	sessionID := c.Param("id")
+	client, exists := h.WAClientManager.GetClient(currentTenant(c).ID, sessionID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
+		return
+	}
+
+	limits := client.RateLimits().Limits
+	if err := c.ShouldBindJSON(&limits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos", "details": err.Error()})
+		return
+	}
+	h.saveRateLimits(c, sessionID, &limits)
+}
+
+// ResetSessionRateLimits volta a sessão aos limites de envio padrão do servidor
+func (h *SessionHandler) ResetSessionRateLimits(c *gin.Context) {
+	h.saveRateLimits(c, c.Param("id"), nil)
+}
+
+func (h *SessionHandler) saveRateLimits(c *gin.Context, sessionID string, limits *models.RateLimits) {
+	err := h.WAClientManager.SetSessionRateLimits(currentTenant(c).ID, sessionID, limits)
+	if errors.Is(err, whatsapp.ErrInvalidRateLimits) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Erro ao alterar limites de envio da sessão %s: %v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao alterar limites de envio"})
+		return
+	}
+
+	if client, exists := h.WAClientManager.GetClient(currentTenant(c).ID, sessionID); exists {
+		c.JSON(http.StatusOK, client.RateLimits())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reconcileAuditLimit limita as ações retornadas por GetReconcileAudit
+const reconcileAuditLimit = 200
+
+// requireOperator restringe a rota à chave de administrador (ADMIN_API_KEY),
+// que opera o servidor inteiro; o reconciliador age sobre as sessões de todos
+// os workspaces
+func requireOperator(c *gin.Context) bool {
+	if !c.GetBool(operatorKey) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Disponível apenas com a chave de administrador"})
+		return false
+	}
+	return true
+}
+
+// RunReconcile executa o reconciliador imediatamente. ?dry_run=true apenas
+// registra o que seria removido; sem o parâmetro vale o modo configurado.
+func (h *SessionHandler) RunReconcile(c *gin.Context) {
+	if !requireOperator(c) {
+		return
+	}
+
+	dryRun := h.WAClientManager.Reconciler.DryRun
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run inválido"})
+			return
+		}
+		dryRun = parsed
+	}
+
+	report, err := h.WAClientManager.Reconcile(dryRun)
+	if err != nil {
+		log.Printf("[Reconcile] Erro na reconciliação manual: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro na reconciliação: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetReconcileAudit retorna as últimas ações do reconciliador
+func (h *SessionHandler) GetReconcileAudit(c *gin.Context) {
+	if !requireOperator(c) {
+		return
+	}
+
+	audit, err := h.DB.GetReconcileAudit(reconcileAuditLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao ler auditoria"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"actions": audit})
+}
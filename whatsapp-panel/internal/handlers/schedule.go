@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/services/whatsapp"
+	"whatsapp-panel/internal/storage"
+)
+
+// CreateSchedule agenda uma mensagem da sessão para um horário ("send_at") ou
+// de forma recorrente ("cron"), no fuso "timezone". O texto vem de "message"
+// ou do modelo "template_id", renderizado a cada envio com "variables" e com
+// os dados do contato.
+func (h *WhatsAppHandler) CreateSchedule(c *gin.Context) {
+	var req struct {
+		PhoneNumber string            `json:"phone_number" binding:"required"`
+		Message     string            `json:"message"`
+		TemplateID  string            `json:"template_id"`
+		Variables   map[string]string `json:"variables"`
+		SendAt      string            `json:"send_at"`
+		Cron        string            `json:"cron"`
+		Timezone    string            `json:"timezone"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Dados inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	schedule, err := h.WAClientManager.CreateSchedule(currentTenant(c).ID, c.Param("id"), whatsapp.ScheduleRequest{
+		PhoneNumber: req.PhoneNumber,
+		Message:     req.Message,
+		TemplateID:  req.TemplateID,
+		Variables:   req.Variables,
+		SendAt:      req.SendAt,
+		Cron:        req.Cron,
+		Timezone:    req.Timezone,
+	})
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// ListSchedules lista os agendamentos da sessão, do mais recente para o mais
+// antigo; ?status= filtra por estado
+func (h *WhatsAppHandler) ListSchedules(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.ScheduleActive, models.ScheduleCompleted, models.ScheduleFailed, models.ScheduleCancelled:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status inválido (use active, completed, failed ou cancelled)"})
+		return
+	}
+
+	schedules, err := h.DB.ListSchedules(currentTenant(c).ID, c.Param("id"), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar agendamentos"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedules": schedules})
+}
+
+// GetSchedule retorna um agendamento com o resultado da última execução
+func (h *WhatsAppHandler) GetSchedule(c *gin.Context) {
+	schedule, err := h.WAClientManager.GetSchedule(currentTenant(c).ID, c.Param("id"), c.Param("schedule_id"))
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// CancelSchedule cancela um agendamento ativo
+func (h *WhatsAppHandler) CancelSchedule(c *gin.Context) {
+	schedule, err := h.WAClientManager.CancelSchedule(currentTenant(c).ID, c.Param("id"), c.Param("schedule_id"))
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// scheduleError responde com o status HTTP correspondente a um erro de agendamento
+func scheduleError(c *gin.Context, err error) {
+	var missing *whatsapp.MissingVariablesError
+	switch {
+	case errors.As(err, &missing), errors.Is(err, storage.ErrTemplateNotFound):
+		templateError(c, err)
+	case errors.Is(err, storage.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
+	case errors.Is(err, storage.ErrScheduleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agendamento não encontrado"})
+	case errors.Is(err, whatsapp.ErrInvalidSchedule), errors.Is(err, whatsapp.ErrInvalidPhone):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrScheduleState):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Erro ao processar agendamento",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/services/whatsapp"
+	"whatsapp-panel/internal/storage"
+)
+
+// templateRequest são os campos de criação e alteração de um modelo
+type templateRequest struct {
+	Name string `json:"name"`
+	Body string `json:"body"`
+}
+
+// ListTemplates lista os modelos de mensagem do tenant
+func (h *WhatsAppHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.DB.ListTemplates(currentTenant(c).ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar modelos"})
+		return
+	}
+	for i := range templates {
+		templates[i].Variables = whatsapp.MessageVariables(templates[i].Body)
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+// CreateTemplate cria um modelo de mensagem
+func (h *WhatsAppHandler) CreateTemplate(c *gin.Context) {
+	var req templateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Dados inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	t := &models.MessageTemplate{
+		ID:       uuid.New().String(),
+		TenantID: currentTenant(c).ID,
+		Name:     req.Name,
+		Body:     req.Body,
+	}
+	if err := whatsapp.ValidateTemplate(t); err != nil {
+		templateError(c, err)
+		return
+	}
+	if err := h.DB.CreateTemplate(t); err != nil {
+		templateError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, t)
+}
+
+// GetTemplate retorna um modelo de mensagem com as variáveis usadas no texto
+func (h *WhatsAppHandler) GetTemplate(c *gin.Context) {
+	t, err := h.DB.GetTemplate(currentTenant(c).ID, c.Param("id"))
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+	t.Variables = whatsapp.MessageVariables(t.Body)
+	c.JSON(http.StatusOK, t)
+}
+
+// UpdateTemplate altera um modelo de mensagem; campos omitidos são mantidos
+func (h *WhatsAppHandler) UpdateTemplate(c *gin.Context) {
+	var req struct {
+		Name *string `json:"name"`
+		Body *string `json:"body"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Dados inválidos",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	t, err := h.DB.GetTemplate(currentTenant(c).ID, c.Param("id"))
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+	if req.Name != nil {
+		t.Name = *req.Name
+	}
+	if req.Body != nil {
+		t.Body = *req.Body
+	}
+	if err := whatsapp.ValidateTemplate(t); err != nil {
+		templateError(c, err)
+		return
+	}
+	if err := h.DB.UpdateTemplate(t); err != nil {
+		templateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// DeleteTemplate remove um modelo de mensagem
+func (h *WhatsAppHandler) DeleteTemplate(c *gin.Context) {
+	if err := h.DB.DeleteTemplate(currentTenant(c).ID, c.Param("id")); err != nil {
+		templateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Modelo removido"})
+}
+
+// templateError responde com o status HTTP correspondente a um erro de modelo
+// ou de renderização. Variáveis sem valor são listadas em "missing_variables".
+func templateError(c *gin.Context, err error) {
+	var missing *whatsapp.MissingVariablesError
+	switch {
+	case errors.As(err, &missing):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             err.Error(),
+			"missing_variables": missing.Names,
+		})
+	case errors.Is(err, storage.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Modelo não encontrado"})
+	case errors.Is(err, storage.ErrTemplateExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, whatsapp.ErrInvalidTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Erro ao processar modelo",
+			"details": err.Error(),
+		})
+	}
+}
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
+	"whatsapp-panel/internal/models"
 	"whatsapp-panel/internal/services/whatsapp"
 	"whatsapp-panel/internal/storage"
 )
@@ -23,7 +25,7 @@ func NewWhatsAppHandler(manager *whatsapp.Manager, db *storage.Database) *WhatsA
 }
 
 func (h *WhatsAppHandler) Index(c *gin.Context) {
-	sessions, err := h.DB.GetAllSessions()
+	sessions, err := h.DB.GetAllSessions(currentTenant(c).ID, c.QueryArray("tag")...)
 	if err != nil {
 		log.Printf("Erro ao carregar sessões: %v", err)
 		c.HTML(http.StatusOK, "error.html", gin.H{
@@ -32,15 +34,18 @@ func (h *WhatsAppHandler) Index(c *gin.Context) {
 		return
 	}
 
+	applyLiveStatus(h.WAClientManager, sessions)
+
 	c.HTML(http.StatusOK, "index.html", gin.H{
 		"Sessions": sessions,
+		"Tenant":   currentTenant(c),
 		"Title":    "Painel Principal",
 	})
 }
 
 // GetStats retorna estatísticas gerais do sistema
 func (h *WhatsAppHandler) GetStats(c *gin.Context) {
-	sessions, err := h.DB.GetAllSessions()
+	sessions, err := h.DB.GetAllSessions(currentTenant(c).ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao carregar estatísticas"})
 		return
@@ -54,70 +59,23 @@ func (h *WhatsAppHandler) GetStats(c *gin.Context) {
 		TotalMessages  int `json:"total_messages"`
 	}
 
+	applyLiveStatus(h.WAClientManager, sessions)
+
 	for _, session := range sessions {
 		stats.TotalSessions++
-		if status, ok := session["status"].(string); ok && status == "connected" {
+		if status, ok := session["Status"].(string); ok && status == models.StatusConnected {
 			stats.ActiveSessions++
 		}
-		if sessionStats, ok := session["Stats"].(map[string]interface{}); ok {
-			if contacts, ok := sessionStats["Contacts"].(int); ok {
-				stats.TotalContacts += contacts
-			}
-			if groups, ok := sessionStats["Groups"].(int); ok {
-				stats.TotalGroups += groups
-			}
-			if messages, ok := sessionStats["MessageCount"].(int); ok {
-				stats.TotalMessages += messages
-			}
+		if sessionStats, ok := session["Stats"].(map[string]int); ok {
+			stats.TotalContacts += sessionStats["Contacts"]
+			stats.TotalGroups += sessionStats["Groups"]
+			stats.TotalMessages += sessionStats["MessageCount"]
 		}
 	}
 
 	c.JSON(http.StatusOK, stats)
 }
 
-// GetSessionInfo retorna informações detalhadas de uma sessão específica
-func (h *WhatsAppHandler) GetSessionInfo(c *gin.Context) {
-	sessionID := c.Param("id")
-	if sessionID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID da sessão não fornecido"})
-		return
-	}
-
-	h.WAClientManager.Mutex.Lock()
-	client, exists := h.WAClientManager.Clients[sessionID]
-	h.WAClientManager.Mutex.Unlock()
-
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
-		return
-	}
-
-	// Buscar informações da sessão no banco de dados
-	sessions, err := h.DB.GetAllSessions()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar informações da sessão"})
-		return
-	}
-
-	var sessionInfo map[string]interface{}
-	for _, s := range sessions {
-		if id, ok := s["ID"].(string); ok && id == sessionID {
-			sessionInfo = s
-			break
-		}
-	}
-
-	if sessionInfo == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Informações da sessão não encontradas"})
-		return
-	}
-
-	// Adicionar status de conexão atual
-	sessionInfo["is_connected"] = client.Connected
-
-	c.JSON(http.StatusOK, sessionInfo)
-}
-
 // DisconnectSession desconecta uma sessão do WhatsApp
 func (h *WhatsAppHandler) DisconnectSession(c *gin.Context) {
 	sessionID := c.Param("id")
@@ -126,9 +84,7 @@ func (h *WhatsAppHandler) DisconnectSession(c *gin.Context) {
 		return
 	}
 
-	h.WAClientManager.Mutex.Lock()
-	client, exists := h.WAClientManager.Clients[sessionID]
-	h.WAClientManager.Mutex.Unlock()
+	client, exists := h.WAClientManager.GetClient(currentTenant(c).ID, sessionID)
 
 	if !exists {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
@@ -139,7 +95,10 @@ func (h *WhatsAppHandler) DisconnectSession(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
-// SendMessage envia uma mensagem de texto para um número
+// SendMessage enfileira uma mensagem de texto para um número. O texto vem de
+// "message" ou do modelo "template_id", renderizado com "variables" e com os
+// dados do contato ({{nome}}, {{numero}}). A resposta traz o ID da mensagem
+// na fila; a entrega é feita pelo worker da sessão.
 func (h *WhatsAppHandler) SendMessage(c *gin.Context) {
 	sessionID := c.Param("id")
 	if sessionID == "" {
@@ -148,8 +107,10 @@ func (h *WhatsAppHandler) SendMessage(c *gin.Context) {
 	}
 
 	var req struct {
-		PhoneNumber string `json:"phone_number" binding:"required"`
-		Message     string `json:"message" binding:"required"`
+		PhoneNumber string            `json:"phone_number" binding:"required"`
+		Message     string            `json:"message"`
+		TemplateID  string            `json:"template_id"`
+		Variables   map[string]string `json:"variables"`
 	}
 
 	if err := c.BindJSON(&req); err != nil {
@@ -159,29 +120,49 @@ func (h *WhatsAppHandler) SendMessage(c *gin.Context) {
 		})
 		return
 	}
+	if (req.Message == "") == (req.TemplateID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe \"message\" ou \"template_id\""})
+		return
+	}
 
-	h.WAClientManager.Mutex.Lock()
-	client, exists := h.WAClientManager.Clients[sessionID]
-	h.WAClientManager.Mutex.Unlock()
+	tenantID := currentTenant(c).ID
+	text := req.Message
+	if req.TemplateID != "" {
+		client, exists := h.WAClientManager.GetClient(tenantID, sessionID)
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
+			return
+		}
+		var err error
+		text, err = h.WAClientManager.RenderTemplate(tenantID, req.TemplateID, client.ContactVariables(req.PhoneNumber), req.Variables)
+		if err != nil {
+			templateError(c, err)
+			return
+		}
+	}
 
-	if !exists {
+	msg, err := h.WAClientManager.EnqueueMessage(tenantID, sessionID, req.PhoneNumber, text)
+	if err == storage.ErrSessionNotFound {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Sessão não encontrada"})
 		return
 	}
-
-	// Enviar a mensagem
-	err := client.SendTextMessage(req.PhoneNumber, req.Message)
+	if errors.Is(err, whatsapp.ErrInvalidPhone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Erro ao enviar mensagem",
+			"error":   "Erro ao enfileirar mensagem",
 			"details": err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Mensagem enviada com sucesso",
+	c.JSON(http.StatusAccepted, gin.H{
+		"success":    true,
+		"message":    "Mensagem enfileirada para envio",
+		"message_id": msg.ID,
+		"status":     msg.Status,
 	})
 }
 
@@ -195,9 +176,7 @@ func (h *WhatsAppHandler) GetMessageForm(c *gin.Context) {
 		return
 	}
 
-	h.WAClientManager.Mutex.Lock()
-	_, exists := h.WAClientManager.Clients[sessionID]
-	h.WAClientManager.Mutex.Unlock()
+	_, exists := h.WAClientManager.GetClient(currentTenant(c).ID, sessionID)
 
 	if !exists {
 		c.HTML(http.StatusNotFound, "error.html", gin.H{
@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// Estados de uma campanha de envio em massa
+const (
+	CampaignRunning   = "running"   // enviando para os destinatários pendentes
+	CampaignPaused    = "paused"    // pausada pelo usuário, pode ser retomada
+	CampaignCancelled = "cancelled" // cancelada; os pendentes foram ignorados
+	CampaignCompleted = "completed" // todos os destinatários processados
+)
+
+// Estados de um destinatário de campanha
+const (
+	RecipientPending = "pending" // aguardando envio
+	RecipientSent    = "sent"    // aceito pelo WhatsApp
+	RecipientFailed  = "failed"  // erro no envio
+	RecipientSkipped = "skipped" // não enviado (número inválido, repetido, variável vazia ou campanha cancelada)
+)
+
+// CampaignCounts resume os destinatários de uma campanha por estado
+type CampaignCounts struct {
+	Total   int `json:"total"`
+	Pending int `json:"pending"`
+	Sent    int `json:"sent"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// Add conta um destinatário no estado informado
+func (c *CampaignCounts) Add(status string) {
+	c.Total++
+	switch status {
+	case RecipientPending:
+		c.Pending++
+	case RecipientSent:
+		c.Sent++
+	case RecipientFailed:
+		c.Failed++
+	case RecipientSkipped:
+		c.Skipped++
+	}
+}
+
+// Campaign é um envio em massa de uma mensagem para uma lista de contatos
+type Campaign struct {
+	ID         string         `json:"id"`
+	TenantID   string         `json:"-"`
+	SessionID  string         `json:"session_id"`
+	Name       string         `json:"name"`
+	Message    string         `json:"message"`
+	TemplateID string         `json:"template_id,omitempty"` // modelo de onde veio a mensagem, se houver
+	Status     string         `json:"status"`
+	Counts     CampaignCounts `json:"counts"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+}
+
+// CampaignRecipient é um contato da lista de uma campanha
+type CampaignRecipient struct {
+	ID          int64             `json:"id"`
+	CampaignID  string            `json:"-"`
+	PhoneNumber string            `json:"phone_number"`
+	Name        string            `json:"name,omitempty"`
+	Fields      map[string]string `json:"fields,omitempty"` // colunas do CSV, usadas nas variáveis da mensagem
+	Status      string            `json:"status"`
+	Error       string            `json:"error,omitempty"`
+	WAMessageID string            `json:"wa_message_id,omitempty"`
+	SentAt      *time.Time        `json:"sent_at,omitempty"`
+}
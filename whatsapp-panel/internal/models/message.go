@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Estados de entrega de uma mensagem enviada. A partir de MessageSent o
+// estado só avança, conforme os recibos do WhatsApp.
+const (
+	MessageQueued    = "queued"    // na fila de envio
+	MessageFailed    = "failed"    // não enviada (dead-letter)
+	MessageSent      = "sent"      // aceita pelo servidor do WhatsApp
+	MessageDelivered = "delivered" // entregue no aparelho do destinatário
+	MessageRead      = "read"      // lida pelo destinatário
+	MessagePlayed    = "played"    // áudio ou vídeo reproduzido
+	MessageReceived  = "received"  // mensagem recebida pela sessão
+)
+
+// Direção de uma mensagem em relação à sessão
+const (
+	MessageInbound  = "inbound"
+	MessageOutbound = "outbound" // enviada pelo painel ou pelo próprio aparelho
+)
+
+// MessageMedia referencia a mídia de uma mensagem nos servidores do WhatsApp
+type MessageMedia struct {
+	MimeType   string `json:"mimetype,omitempty"`
+	FileName   string `json:"filename,omitempty"`
+	Size       int64  `json:"size,omitempty"`
+	DirectPath string `json:"direct_path,omitempty"`
+}
+
+// Message é uma mensagem enviada ou recebida por uma sessão. As enviadas
+// guardam os horários dos recibos de entrega, leitura e reprodução.
+type Message struct {
+	ID              string        `json:"id"`
+	SessionID       string        `json:"session_id"`
+	TenantID        string        `json:"-"`
+	WAMessageID     string        `json:"wa_message_id,omitempty"`
+	Direction       string        `json:"direction"`
+	ChatJID         string        `json:"chat_jid,omitempty"`
+	SenderJID       string        `json:"sender_jid,omitempty"`
+	SenderName      string        `json:"sender_name,omitempty"`
+	PhoneNumber     string        `json:"phone_number,omitempty"`
+	Type            string        `json:"type"` // text, image, video, audio, document, sticker, location ou contact
+	Body            string        `json:"message,omitempty"`
+	Media           *MessageMedia `json:"media,omitempty"`
+	QuotedMessageID string        `json:"quoted_message_id,omitempty"` // ID do WhatsApp da mensagem respondida
+	Status          string        `json:"status"`
+	Error           string        `json:"error,omitempty"`
+	Timestamp       time.Time     `json:"timestamp"`
+	SentAt          *time.Time    `json:"sent_at,omitempty"`
+	DeliveredAt     *time.Time    `json:"delivered_at,omitempty"`
+	ReadAt          *time.Time    `json:"read_at,omitempty"`
+	PlayedAt        *time.Time    `json:"played_at,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// Chat é uma conversa da sessão, com a última mensagem trocada
+type Chat struct {
+	JID          string  `json:"jid"`
+	Name         string  `json:"name,omitempty"`
+	MessageCount int     `json:"message_count"`
+	LastMessage  Message `json:"last_message"`
+}
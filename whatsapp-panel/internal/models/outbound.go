@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Estados de uma mensagem na fila de envio
+const (
+	OutboundQueued  = "queued"  // aguardando envio (ou nova tentativa)
+	OutboundSending = "sending" // sendo enviada por um worker
+	OutboundSent    = "sent"    // aceita pelo WhatsApp
+	OutboundDead    = "dead"    // tentativas esgotadas ou erro permanente
+)
+
+// OutboundMessage é uma mensagem de texto na fila de envio durável
+type OutboundMessage struct {
+	ID            string     `json:"id"`
+	SessionID     string     `json:"session_id"`
+	TenantID      string     `json:"-"`
+	PhoneNumber   string     `json:"phone_number"`
+	Body          string     `json:"message"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	WAMessageID   string     `json:"wa_message_id,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// RateLimits limita os envios de uma sessão para reduzir o risco de
+// banimento do número. Zero desativa o limite correspondente.
+type RateLimits struct {
+	PerMinute  int `json:"per_minute"`
+	PerHour    int `json:"per_hour"`
+	PerDay     int `json:"per_day"`
+	MinDelayMS int `json:"min_delay_ms"` // atraso mínimo entre dois envios
+	MaxDelayMS int `json:"max_delay_ms"` // atraso máximo; o atraso é sorteado entre os dois
+}
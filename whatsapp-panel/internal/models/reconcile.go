@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Ações do reconciliador de sessões
+const (
+	ReconcileRemovePendingClient     = "remove_pending_client"
+	ReconcileRemoveFileWithoutDevice = "remove_file_without_device"
+	ReconcileRemoveRowWithoutFile    = "remove_row_without_file"
+)
+
+// ReconcileAction registra uma ação do reconciliador na tabela de auditoria.
+// Em modo de simulação (DryRun) a ação é registrada, mas não executada.
+type ReconcileAction struct {
+	RunID     string    `json:"run_id"`
+	Action    string    `json:"action"`
+	SessionID string    `json:"session_id"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	Reason    string    `json:"reason"`
+	DryRun    bool      `json:"dry_run"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
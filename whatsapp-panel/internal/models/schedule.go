@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Estados de um agendamento de mensagem
+const (
+	ScheduleActive    = "active"    // aguardando a próxima execução
+	ScheduleCompleted = "completed" // envio único já executado
+	ScheduleFailed    = "failed"    // envio único que não pôde ser enfileirado
+	ScheduleCancelled = "cancelled" // cancelado pela API
+)
+
+// ScheduledMessage é um envio agendado para um horário (SendAt) ou
+// recorrente (Cron), sempre interpretado no fuso Timezone
+type ScheduledMessage struct {
+	ID            string            `json:"id"`
+	SessionID     string            `json:"session_id"`
+	TenantID      string            `json:"-"`
+	PhoneNumber   string            `json:"phone_number"`
+	Body          string            `json:"message,omitempty"`
+	TemplateID    string            `json:"template_id,omitempty"`
+	Variables     map[string]string `json:"variables,omitempty"`
+	SendAt        *time.Time        `json:"send_at,omitempty"`
+	Cron          string            `json:"cron,omitempty"`
+	Timezone      string            `json:"timezone"`
+	Status        string            `json:"status"`
+	NextRunAt     *time.Time        `json:"next_run_at,omitempty"`
+	LastRunAt     *time.Time        `json:"last_run_at,omitempty"`
+	LastMessageID string            `json:"last_message_id,omitempty"` // mensagem da fila de envio criada na última execução
+	LastError     string            `json:"last_error,omitempty"`
+	RunCount      int               `json:"run_count"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
@@ -13,8 +13,19 @@ type Session struct {
 	ConnectedAt time.Time `json:"connected_at"`
 	LastActive  time.Time `json:"last_active"`
 	Status      string    `json:"status"` // connected, disconnected, etc.
-	Stats       Stats     `json:"stats"`
-	CreatedAt   time.Time `json:"created_at"`
+	Notes       string    `json:"notes"`
+	Proxy       string    `json:"proxy,omitempty"` // URL do proxy de saída, com credenciais
+	// RateLimits são os limites de envio próprios da sessão (nil = padrão do servidor)
+	RateLimits *RateLimits `json:"rate_limits,omitempty"`
+	Tags       []string    `json:"tags"`
+	Stats      Stats       `json:"stats"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// SessionRef identifica o workspace e o status de uma sessão gravada
+type SessionRef struct {
+	TenantID string
+	Status   string
 }
 
 // Stats contém estatísticas de uma sessão do WhatsApp
@@ -37,6 +48,15 @@ const (
 	StatusPending      = "pending"
 )
 
+// StatusTransition registra uma mudança de status de uma sessão
+type StatusTransition struct {
+	SessionID string    `json:"session_id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // NewSession cria uma nova sessão
 func NewSession(id, name, jid, phoneNumber string) *Session {
 	now := time.Now()
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// MessageTemplate é um texto salvo, com variáveis no formato {{nome}}
+type MessageTemplate struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"-"`
+	Name      string    `json:"name"`
+	Body      string    `json:"body"`
+	Variables []string  `json:"variables"` // variáveis usadas no texto, calculadas a partir de Body
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
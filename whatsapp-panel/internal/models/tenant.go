@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// DefaultTenantID é o workspace da chave de administrador e o dono das
+// sessões criadas antes da existência de workspaces
+const DefaultTenantID = "default"
+
+// Tenant representa um workspace (cliente da agência). Sessões pertencem a um
+// tenant; contatos, estatísticas e histórico de status pertencem à sessão e,
+// por meio dela, ao tenant.
+type Tenant struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	APIKey      string    `json:"-"`
+	MaxSessions int       `json:"max_sessions"` // 0 = sem limite
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// HasQuota informa se o tenant ainda pode criar uma sessão, dado o número atual
+func (t *Tenant) HasQuota(current int) bool {
+	return t.MaxSessions <= 0 || current < t.MaxSessions
+}
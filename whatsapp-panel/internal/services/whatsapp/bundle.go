@@ -0,0 +1,384 @@
+package whatsapp
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"golang.org/x/crypto/scrypt"
+
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/storage"
+)
+
+// Um pacote de sessão é um .tar.gz com o manifesto, a linha da sessão no
+// banco do painel (com notas, tags e estatísticas) e o banco whatsmeow do
+// dispositivo. Com senha, o .tar.gz inteiro é cifrado com AES-256-GCM usando
+// uma chave derivada por scrypt.
+const (
+	bundleVersion      = 1
+	bundleManifestFile = "manifest.json"
+	bundleSessionFile  = "session.json"
+	bundleDeviceFile   = "device.db"
+
+	// MaxBundleSize limita o tamanho de um pacote importado
+	MaxBundleSize = 256 << 20
+)
+
+// bundleEncryptedMagic identifica um pacote cifrado
+var bundleEncryptedMagic = []byte("WAPANEL-BUNDLE-ENC1\n")
+
+var (
+	// ErrBundlePasswordRequired indica um pacote cifrado importado sem senha
+	ErrBundlePasswordRequired = errors.New("pacote protegido por senha")
+	// ErrBundleInvalid indica um pacote corrompido, adulterado ou incompatível
+	ErrBundleInvalid = errors.New("pacote inválido")
+)
+
+// BundleManifest descreve o conteúdo de um pacote e o hash SHA-256 de cada arquivo
+type BundleManifest struct {
+	Version    int               `json:"version"`
+	SessionID  string            `json:"session_id"`
+	JID        string            `json:"jid"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Files      map[string]string `json:"files"`
+}
+
+// ExportBundle grava em w o pacote da sessão do tenant, cifrado se password
+// não for vazio. A sessão continua ativa neste servidor: remova-a depois de
+// importá-la no destino para que o número não fique em uso em dois lugares.
+func ExportBundle(db *storage.Database, sessions *SessionStore, tenantID, sessionID, password string, w io.Writer) error {
+	session, err := db.GetSession(tenantID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	device, err := sessions.ReadImage(sessionID)
+	if err != nil {
+		return err
+	}
+
+	// Credenciais do proxy só viajam em pacotes cifrados
+	if password == "" && proxyHasCredentials(session.Proxy) {
+		log.Printf("[Bundle] Pacote da sessão %s sem senha: proxy com credenciais não exportado", sessionID)
+		session.Proxy = ""
+	}
+
+	sessionJSON, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar sessão: %v", err)
+	}
+
+	files := map[string][]byte{
+		bundleSessionFile: sessionJSON,
+		bundleDeviceFile:  device,
+	}
+	manifest := BundleManifest{
+		Version:    bundleVersion,
+		SessionID:  session.ID,
+		JID:        session.JID,
+		ExportedAt: time.Now(),
+		Files:      make(map[string]string, len(files)),
+	}
+	for name, data := range files {
+		manifest.Files[name] = sha256Hex(data)
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar manifesto: %v", err)
+	}
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{bundleManifestFile, bundleSessionFile, bundleDeviceFile} {
+		data := manifestJSON
+		if name != bundleManifestFile {
+			data = files[name]
+		}
+		header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: manifest.ExportedAt}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("erro ao gravar pacote: %v", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("erro ao gravar pacote: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("erro ao gravar pacote: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("erro ao gravar pacote: %v", err)
+	}
+
+	out := archive.Bytes()
+	if password != "" {
+		if out, err = sealBundle(out, password); err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write(out)
+	return err
+}
+
+// ImportBundle verifica a integridade do pacote (senha, hashes do manifesto e
+// dispositivo pareado com o mesmo JID da sessão) e só então grava o banco
+// whatsmeow no diretório de sessões e a sessão no tenant. A sessão importada
+// fica com status paired até ser conectada.
+func ImportBundle(db *storage.Database, sessions *SessionStore, tenantID string, r io.Reader, password string) (*models.Session, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxBundleSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler pacote: %v", err)
+	}
+	if len(data) > MaxBundleSize {
+		return nil, fmt.Errorf("%w: pacote maior que %d MB", ErrBundleInvalid, MaxBundleSize>>20)
+	}
+
+	if bytes.HasPrefix(data, bundleEncryptedMagic) {
+		if password == "" {
+			return nil, ErrBundlePasswordRequired
+		}
+		if data, err = openBundle(data, password); err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := readBundleFiles(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest BundleManifest
+	if err := json.Unmarshal(files[bundleManifestFile], &manifest); err != nil {
+		return nil, fmt.Errorf("%w: manifesto ilegível", ErrBundleInvalid)
+	}
+	if manifest.Version != bundleVersion {
+		return nil, fmt.Errorf("%w: versão %d não suportada", ErrBundleInvalid, manifest.Version)
+	}
+	for _, name := range []string{bundleSessionFile, bundleDeviceFile} {
+		if _, ok := manifest.Files[name]; !ok {
+			return nil, fmt.Errorf("%w: %s ausente do manifesto", ErrBundleInvalid, name)
+		}
+	}
+	for name, sum := range manifest.Files {
+		content, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: arquivo %s ausente", ErrBundleInvalid, name)
+		}
+		if sha256Hex(content) != sum {
+			return nil, fmt.Errorf("%w: hash de %s não confere", ErrBundleInvalid, name)
+		}
+	}
+
+	var session models.Session
+	if err := json.Unmarshal(files[bundleSessionFile], &session); err != nil {
+		return nil, fmt.Errorf("%w: sessão ilegível", ErrBundleInvalid)
+	}
+	if session.ID != manifest.SessionID || session.JID != manifest.JID {
+		return nil, fmt.Errorf("%w: sessão não confere com o manifesto", ErrBundleInvalid)
+	}
+	if _, err := uuid.Parse(session.ID); err != nil {
+		return nil, fmt.Errorf("%w: ID de sessão inválido", ErrBundleInvalid)
+	}
+
+	if _, err := os.Stat(sessions.Path(session.ID)); err == nil {
+		return nil, storage.ErrSessionExists
+	}
+
+	// Verificar o dispositivo em memória antes de gravá-lo no diretório de sessões
+	if err := verifyBundleDevice(files[bundleDeviceFile], session.JID); err != nil {
+		return nil, err
+	}
+
+	// Gravar pelo SessionStore, que cifra o arquivo se houver chave configurada
+	if err := sessions.WriteImage(session.ID, files[bundleDeviceFile]); err != nil {
+		return nil, fmt.Errorf("erro ao gravar arquivo de sessão: %v", err)
+	}
+
+	session.Status = models.StatusPaired
+	if err := db.InsertSession(tenantID, &session); err != nil {
+		sessions.Remove(session.ID)
+		return nil, err
+	}
+
+	log.Printf("[Bundle] Sessão %s (%s) importada no workspace %s", session.ID, session.JID, tenantID)
+	return &session, nil
+}
+
+// verifyBundleDevice confere se a imagem do banco whatsmeow contém um
+// dispositivo pareado com o JID informado, sem gravá-la em disco
+func verifyBundleDevice(image []byte, jid string) error {
+	if !bytes.HasPrefix(image, sqliteMagic) {
+		return fmt.Errorf("%w: banco do dispositivo ilegível", ErrBundleInvalid)
+	}
+	container := sqlstore.NewWithDB(openImage(image), "sqlite3", waLog.Noop)
+	defer container.Close()
+
+	device, err := container.GetFirstDevice()
+	if err != nil || device == nil || device.ID == nil {
+		return fmt.Errorf("%w: banco não contém dispositivo pareado", ErrBundleInvalid)
+	}
+	if device.ID.ToNonAD().String() != jid {
+		return fmt.Errorf("%w: dispositivo %s não pertence à sessão %s", ErrBundleInvalid, device.ID.ToNonAD(), jid)
+	}
+	return nil
+}
+
+// readBundleFiles extrai os arquivos do .tar.gz, recusando caminhos e
+// arquivos inesperados
+func readBundleFiles(data []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: arquivo não é um pacote de sessão", ErrBundleInvalid)
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: arquivo corrompido", ErrBundleInvalid)
+		}
+		if header.Typeflag != tar.TypeReg || filepath.Base(header.Name) != header.Name {
+			return nil, fmt.Errorf("%w: entrada inesperada %q", ErrBundleInvalid, header.Name)
+		}
+		content, err := io.ReadAll(io.LimitReader(tr, MaxBundleSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: arquivo corrompido", ErrBundleInvalid)
+		}
+		if len(content) > MaxBundleSize {
+			return nil, fmt.Errorf("%w: %s maior que %d MB", ErrBundleInvalid, header.Name, MaxBundleSize>>20)
+		}
+		files[header.Name] = content
+	}
+
+	if _, ok := files[bundleManifestFile]; !ok {
+		return nil, fmt.Errorf("%w: manifesto ausente", ErrBundleInvalid)
+	}
+	return files, nil
+}
+
+// sealBundle cifra o pacote: magic | salt | nonce | AES-256-GCM(pacote)
+func sealBundle(data []byte, password string) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("erro ao gerar salt: %v", err)
+	}
+	gcm, err := bundleCipher(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("erro ao gerar nonce: %v", err)
+	}
+
+	out := append([]byte{}, bundleEncryptedMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, bundleEncryptedMagic), nil
+}
+
+// openBundle decifra um pacote gerado por sealBundle
+func openBundle(data []byte, password string) ([]byte, error) {
+	data = data[len(bundleEncryptedMagic):]
+	if len(data) < 16 {
+		return nil, fmt.Errorf("%w: pacote cifrado truncado", ErrBundleInvalid)
+	}
+	salt, data := data[:16], data[16:]
+
+	gcm, err := bundleCipher(password, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: pacote cifrado truncado", ErrBundleInvalid)
+	}
+	nonce, data := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	plain, err := gcm.Open(nil, nonce, data, bundleEncryptedMagic)
+	if err != nil {
+		return nil, fmt.Errorf("%w: senha incorreta ou pacote adulterado", ErrBundleInvalid)
+	}
+	return plain, nil
+}
+
+func bundleCipher(password string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(password), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao derivar chave: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// proxyHasCredentials informa se a URL do proxy contém usuário ou senha
+func proxyHasCredentials(proxyURL string) bool {
+	u, err := url.Parse(proxyURL)
+	return err == nil && u.User != nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ExportSession grava o pacote de uma sessão do tenant (ver ExportBundle)
+func (m *Manager) ExportSession(tenantID, sessionID, password string, w io.Writer) error {
+	return ExportBundle(m.DB, m.Sessions, tenantID, sessionID, password, w)
+}
+
+// ImportSession importa um pacote no tenant, respeitando a cota de sessões, e
+// registra a sessão no gerenciador, reconectando-a em segundo plano
+func (m *Manager) ImportSession(tenantID string, r io.Reader, password string) (*Client, error) {
+	m.createMu.Lock()
+	defer m.createMu.Unlock()
+
+	if err := m.CheckQuota(tenantID); err != nil {
+		return nil, err
+	}
+
+	session, err := ImportBundle(m.DB, m.Sessions, tenantID, r, password)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := m.restoreClient(session.ID, waLog.Stdout("whatsmeow", "DEBUG", true))
+	if err != nil {
+		// Desfazer a importação, para que o pacote possa ser importado de novo
+		if rmErr := m.removeSessionFile(session.ID); rmErr != nil {
+			log.Printf("[Bundle] Erro ao remover arquivo da sessão %s não restaurada: %v", session.ID, rmErr)
+		}
+		if delErr := m.DB.DeleteSession(tenantID, session.ID); delErr != nil {
+			log.Printf("[Bundle] Erro ao remover sessão %s não restaurada: %v", session.ID, delErr)
+		}
+		return nil, err
+	}
+	go m.reconnectRestored(client)
+	return client, nil
+}
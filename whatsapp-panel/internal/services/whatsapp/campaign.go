@@ -0,0 +1,597 @@
+package whatsapp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/storage"
+)
+
+// Campanhas enviam uma mensagem para uma lista de contatos importada de CSV
+// (o formato de cmd/exportcontacts ou uma planilha com coluna de telefone).
+// Cada campanha em andamento tem um worker que envia aos destinatários
+// pendentes, um por vez, dentro dos limites de envio da sessão. O estado de
+// cada destinatário fica no banco, então a campanha continua após um reinício.
+
+var (
+	// ErrInvalidCampaign indica uma campanha ou lista de contatos inválida
+	ErrInvalidCampaign = errors.New("campanha inválida")
+	// ErrCampaignState indica uma operação incompatível com o estado da campanha
+	ErrCampaignState = errors.New("operação não permitida no estado atual da campanha")
+)
+
+// Variáveis preenchidas em todo destinatário, além das colunas do CSV
+const (
+	campaignVarName   = "nome"
+	campaignVarNumber = "numero"
+)
+
+// Colunas reconhecidas como telefone e nome do contato, em ordem de preferência
+var (
+	contactPhoneColumns = []string{"number", "numero", "número", "phone", "phone_number", "telefone", "celular", "whatsapp", "jid"}
+	contactNameColumns  = []string{"pushname", "name", "nome"}
+)
+
+// CampaignPolicy limita as campanhas de envio em massa
+type CampaignPolicy struct {
+	MaxRecipients int // destinatários por campanha (0 = sem limite)
+}
+
+// ContactList é uma lista de contatos lida de um CSV
+type ContactList struct {
+	Variables  []string // variáveis disponíveis para a mensagem
+	Recipients []models.CampaignRecipient
+}
+
+// ParseContactList lê um CSV com cabeçalho, separado por vírgula ou ponto e
+// vírgula. O telefone vem da coluna Number (ou telefone, phone, JID...) e o
+// nome da coluna PushName (ou nome); cada coluna vira uma variável da
+// mensagem, com o nome do cabeçalho em minúsculas. Números inválidos,
+// repetidos ou que não são de contatos individuais entram como ignorados.
+func ParseContactList(r io.Reader) (*ContactList, error) {
+	br := bufio.NewReader(r)
+	firstLine, err := br.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	firstLine = bytes.TrimPrefix(firstLine, []byte("\ufeff"))
+
+	reader := csv.NewReader(io.MultiReader(bytes.NewReader(firstLine), br))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	if bytes.Contains(firstLine, []byte(";")) && !bytes.Contains(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: arquivo vazio", ErrInvalidCampaign)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCampaign, err)
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+	}
+	phoneCol := findColumn(columns, contactPhoneColumns)
+	if phoneCol < 0 {
+		return nil, fmt.Errorf("%w: nenhuma coluna de telefone no cabeçalho (use Number, telefone ou phone)", ErrInvalidCampaign)
+	}
+	nameCol := findColumn(columns, contactNameColumns)
+
+	list := &ContactList{Recipients: []models.CampaignRecipient{}}
+	seenVar := make(map[string]bool)
+	for _, name := range append(columns, campaignVarName, campaignVarNumber) {
+		if name != "" && !seenVar[name] {
+			seenVar[name] = true
+			list.Variables = append(list.Variables, name)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCampaign, err)
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+
+		fields := make(map[string]string, len(columns)+2)
+		for i, value := range record {
+			if i < len(columns) && columns[i] != "" {
+				fields[columns[i]] = strings.TrimSpace(value)
+			}
+		}
+		recipient := models.CampaignRecipient{
+			PhoneNumber: fields[columns[phoneCol]],
+			Status:      models.RecipientPending,
+		}
+		if nameCol >= 0 {
+			recipient.Name = fields[columns[nameCol]]
+		}
+
+		phone, err := contactPhoneNumber(recipient.PhoneNumber)
+		switch {
+		case err != nil:
+			recipient.Status, recipient.Error = models.RecipientSkipped, err.Error()
+		case seen[phone]:
+			recipient.PhoneNumber = phone
+			recipient.Status, recipient.Error = models.RecipientSkipped, "número repetido na lista"
+		default:
+			seen[phone] = true
+			recipient.PhoneNumber = phone
+		}
+		if _, ok := fields[campaignVarName]; !ok {
+			fields[campaignVarName] = recipient.Name
+		}
+		fields[campaignVarNumber] = recipient.PhoneNumber
+		recipient.Fields = fields
+		list.Recipients = append(list.Recipients, recipient)
+	}
+	return list, nil
+}
+
+// findColumn retorna o índice da primeira coluna candidata presente, ou -1
+func findColumn(columns, candidates []string) int {
+	for _, candidate := range candidates {
+		for i, column := range columns {
+			if column == candidate {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// contactPhoneNumber extrai o número de um telefone formatado (+55 (11) ...)
+// ou de um JID de contato (5511...@s.whatsapp.net)
+func contactPhoneNumber(value string) (string, error) {
+	if user, server, found := strings.Cut(value, "@"); found {
+		if server != "s.whatsapp.net" && server != "c.us" {
+			return "", fmt.Errorf("%w: %s não é um contato individual", ErrInvalidPhone, value)
+		}
+		user, _, _ = strings.Cut(user, ":")
+		value = user
+	}
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+	if !phoneNumberPattern.MatchString(digits) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidPhone, value)
+	}
+	return digits, nil
+}
+
+// campaignWorker envia os destinatários pendentes de uma campanha
+type campaignWorker struct {
+	stop chan struct{} // fechado ao pausar ou cancelar a campanha
+	done chan struct{} // fechado quando o worker termina
+}
+
+// campaignWorkers guarda os workers das campanhas em andamento
+type campaignWorkers struct {
+	mu      sync.Mutex
+	workers map[string]*campaignWorker
+	stop    chan struct{} // nil antes de StartCampaigns e depois de StopCampaigns
+	wg      sync.WaitGroup
+}
+
+// StartCampaigns retoma as campanhas que estavam em andamento. Deve ser
+// chamado depois de RestoreSessions.
+func (m *Manager) StartCampaigns() error {
+	m.campaigns.mu.Lock()
+	defer m.campaigns.mu.Unlock()
+
+	if m.campaigns.stop != nil {
+		return nil
+	}
+	running, err := m.DB.ListRunningCampaigns()
+	if err != nil {
+		return err
+	}
+	m.campaigns.stop = make(chan struct{})
+	m.campaigns.workers = make(map[string]*campaignWorker)
+	for i := range running {
+		m.startCampaignWorker(&running[i])
+	}
+	log.Printf("[Campaign] Campanhas iniciadas (%d em andamento)", len(running))
+	return nil
+}
+
+// StopCampaigns encerra os workers e aguarda o envio em andamento de cada
+// um, até o prazo de ctx. As campanhas continuam em andamento no banco.
+func (m *Manager) StopCampaigns(ctx context.Context) {
+	m.campaigns.mu.Lock()
+	if m.campaigns.stop == nil {
+		m.campaigns.mu.Unlock()
+		return
+	}
+	close(m.campaigns.stop)
+	m.campaigns.stop = nil
+	m.campaigns.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.campaigns.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("[Campaign] Prazo para encerrar os workers expirado")
+	}
+}
+
+// CampaignRequest são os dados de uma nova campanha. A mensagem vem de
+// Message ou do modelo TemplateID, e Variables são valores padrão para
+// variáveis ausentes ou vazias na lista.
+type CampaignRequest struct {
+	SessionID  string
+	Name       string
+	Message    string
+	TemplateID string
+	Variables  map[string]string
+}
+
+// CreateCampaign cria e inicia uma campanha da sessão do tenant. As variáveis
+// da mensagem ({{nome}}, {{numero}}, colunas do CSV ou req.Variables) devem
+// existir na lista; destinatários com alguma delas vazia entram como ignorados.
+func (m *Manager) CreateCampaign(tenantID string, req CampaignRequest, list *ContactList) (*models.Campaign, error) {
+	if _, exists := m.GetClient(tenantID, req.SessionID); !exists {
+		return nil, storage.ErrSessionNotFound
+	}
+	message := req.Message
+	if req.TemplateID != "" {
+		t, err := m.DB.GetTemplate(tenantID, req.TemplateID)
+		if err != nil {
+			return nil, err
+		}
+		message = t.Body
+	}
+	if strings.TrimSpace(message) == "" {
+		return nil, fmt.Errorf("%w: mensagem vazia", ErrInvalidCampaign)
+	}
+
+	defaults := MergeVariables(req.Variables)
+	available := make(map[string]bool, len(list.Variables)+len(defaults))
+	for _, v := range list.Variables {
+		available[v] = true
+	}
+	for v := range defaults {
+		available[v] = true
+	}
+	for _, v := range MessageVariables(message) {
+		if !available[v] {
+			return nil, fmt.Errorf("%w: a variável {{%s}} não existe na lista nem em variables (colunas: %s)", ErrInvalidCampaign, v, strings.Join(list.Variables, ", "))
+		}
+	}
+	if len(list.Recipients) == 0 {
+		return nil, fmt.Errorf("%w: a lista não tem contatos", ErrInvalidCampaign)
+	}
+	if limit := m.Campaigns.MaxRecipients; limit > 0 && len(list.Recipients) > limit {
+		return nil, fmt.Errorf("%w: a lista tem %d contatos, o limite é %d", ErrInvalidCampaign, len(list.Recipients), limit)
+	}
+
+	pending := 0
+	for i := range list.Recipients {
+		r := &list.Recipients[i]
+		if len(defaults) > 0 {
+			r.Fields = MergeVariables(defaults, r.Fields)
+		}
+		if r.Status != models.RecipientPending {
+			continue
+		}
+		if _, err := RenderMessage(message, r.Fields); err != nil {
+			r.Status, r.Error = models.RecipientSkipped, err.Error()
+			continue
+		}
+		pending++
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Campanha " + time.Now().Format("02/01/2006 15:04")
+	}
+	campaign := &models.Campaign{
+		ID:         uuid.New().String(),
+		TenantID:   tenantID,
+		SessionID:  req.SessionID,
+		Name:       name,
+		Message:    message,
+		TemplateID: req.TemplateID,
+		Status:     models.CampaignRunning,
+	}
+	if pending == 0 {
+		// Nada a enviar: a campanha já nasce concluída
+		campaign.Status = models.CampaignCompleted
+	}
+	if err := m.DB.CreateCampaign(campaign, list.Recipients); err != nil {
+		return nil, err
+	}
+	if pending == 0 {
+		return campaign, nil
+	}
+
+	log.Printf("[Campaign %s] Campanha criada na sessão %s com %d destinatários (%d a enviar)", campaign.ID, req.SessionID, len(list.Recipients), pending)
+	m.campaigns.mu.Lock()
+	if m.campaigns.stop != nil {
+		m.startCampaignWorker(campaign)
+	}
+	m.campaigns.mu.Unlock()
+	return campaign, nil
+}
+
+// PauseCampaign pausa uma campanha em andamento; o envio em curso é concluído
+func (m *Manager) PauseCampaign(tenantID, campaignID string) (*models.Campaign, error) {
+	return m.transitionCampaign(tenantID, campaignID, models.CampaignPaused, models.CampaignRunning)
+}
+
+// ResumeCampaign retoma uma campanha pausada
+func (m *Manager) ResumeCampaign(tenantID, campaignID string) (*models.Campaign, error) {
+	return m.transitionCampaign(tenantID, campaignID, models.CampaignRunning, models.CampaignPaused)
+}
+
+// CancelCampaign cancela uma campanha em andamento ou pausada; os
+// destinatários pendentes são marcados como ignorados
+func (m *Manager) CancelCampaign(tenantID, campaignID string) (*models.Campaign, error) {
+	return m.transitionCampaign(tenantID, campaignID, models.CampaignCancelled, models.CampaignRunning, models.CampaignPaused)
+}
+
+// transitionCampaign leva a campanha ao estado to, se ela estiver num dos estados from
+func (m *Manager) transitionCampaign(tenantID, campaignID, to string, from ...string) (*models.Campaign, error) {
+	m.campaigns.mu.Lock()
+	defer m.campaigns.mu.Unlock()
+
+	campaign, err := m.DB.GetCampaign(tenantID, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	allowed := false
+	for _, status := range from {
+		allowed = allowed || campaign.Status == status
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w: campanha %s", ErrCampaignState, campaignStatusLabel(campaign.Status))
+	}
+
+	if err := m.DB.SetCampaignStatus(campaignID, to); err != nil {
+		return nil, err
+	}
+	switch to {
+	case models.CampaignRunning:
+		if m.campaigns.stop != nil {
+			m.startCampaignWorker(campaign)
+		}
+	case models.CampaignCancelled:
+		if _, err := m.DB.SkipPendingRecipients(campaignID, "campanha cancelada"); err != nil {
+			return nil, err
+		}
+		fallthrough
+	default:
+		if w, ok := m.campaigns.workers[campaignID]; ok {
+			stopCampaignWorker(w)
+		}
+	}
+	if to == models.CampaignRunning {
+		log.Printf("[Campaign %s] Campanha retomada", campaignID)
+	} else {
+		log.Printf("[Campaign %s] Campanha %s", campaignID, campaignStatusLabel(to))
+	}
+	return m.DB.GetCampaign(tenantID, campaignID)
+}
+
+func campaignStatusLabel(status string) string {
+	switch status {
+	case models.CampaignRunning:
+		return "em andamento"
+	case models.CampaignPaused:
+		return "pausada"
+	case models.CampaignCancelled:
+		return "cancelada"
+	case models.CampaignCompleted:
+		return "concluída"
+	}
+	return status
+}
+
+func stopCampaignWorker(w *campaignWorker) {
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+}
+
+// startCampaignWorker deve ser chamado com m.campaigns.mu travado. Se um
+// worker anterior da campanha ainda estiver concluindo um envio (campanha
+// pausada e retomada em seguida), o novo aguarda ele terminar.
+func (m *Manager) startCampaignWorker(campaign *models.Campaign) {
+	var prev <-chan struct{}
+	if old, ok := m.campaigns.workers[campaign.ID]; ok {
+		stopCampaignWorker(old)
+		prev = old.done
+	}
+	w := &campaignWorker{stop: make(chan struct{}), done: make(chan struct{})}
+	m.campaigns.workers[campaign.ID] = w
+	m.campaigns.wg.Add(1)
+	go m.runCampaign(*campaign, w, prev, m.campaigns.stop)
+}
+
+func (m *Manager) runCampaign(campaign models.Campaign, w *campaignWorker, prev <-chan struct{}, stop <-chan struct{}) {
+	defer m.campaigns.wg.Done()
+	defer close(w.done)
+	defer m.releaseCampaignWorker(campaign.ID, w)
+
+	if prev != nil {
+		select {
+		case <-prev:
+		case <-w.stop:
+			return
+		case <-stop:
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-stop:
+			return
+		default:
+		}
+
+		wait, finished := m.sendNextRecipient(&campaign, stop)
+		if finished {
+			m.completeCampaign(campaign.ID, w)
+			return
+		}
+		if wait <= 0 {
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-w.stop:
+			timer.Stop()
+			return
+		case <-stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// releaseCampaignWorker remove o registro do worker, se ele não foi substituído
+func (m *Manager) releaseCampaignWorker(campaignID string, w *campaignWorker) {
+	m.campaigns.mu.Lock()
+	defer m.campaigns.mu.Unlock()
+	if m.campaigns.workers[campaignID] == w {
+		delete(m.campaigns.workers, campaignID)
+	}
+}
+
+// completeCampaign marca a campanha como concluída, exceto se ela foi pausada
+// ou cancelada enquanto o último envio terminava
+func (m *Manager) completeCampaign(campaignID string, w *campaignWorker) {
+	m.campaigns.mu.Lock()
+	defer m.campaigns.mu.Unlock()
+
+	select {
+	case <-w.stop:
+		return
+	default:
+	}
+	switch err := m.DB.SetCampaignStatus(campaignID, models.CampaignCompleted); err {
+	case nil:
+		log.Printf("[Campaign %s] Campanha concluída", campaignID)
+	case storage.ErrCampaignNotFound:
+		log.Printf("[Campaign %s] Campanha removida junto com a sessão", campaignID)
+	default:
+		log.Printf("[Campaign %s] Erro ao concluir campanha: %v", campaignID, err)
+	}
+}
+
+// sendNextRecipient envia a mensagem ao próximo destinatário pendente.
+// Retorna quanto esperar antes da próxima chamada e se não há mais pendentes.
+// O atraso entre envios é aguardado até stop ser fechado.
+func (m *Manager) sendNextRecipient(campaign *models.Campaign, stop <-chan struct{}) (time.Duration, bool) {
+	recipient, err := m.DB.NextCampaignRecipient(campaign.ID)
+	if err != nil {
+		log.Printf("[Campaign %s] Erro ao ler destinatários: %v", campaign.ID, err)
+		return outboxPollInterval, false
+	}
+	if recipient == nil {
+		return 0, true
+	}
+
+	m.Mutex.Lock()
+	client, exists := m.Clients[campaign.SessionID]
+	m.Mutex.Unlock()
+	if !exists || client.Status() != models.StatusConnected {
+		return outboxPollInterval, false
+	}
+
+	text, err := RenderMessage(campaign.Message, recipient.Fields)
+	if err != nil {
+		m.finishRecipient(campaign.ID, recipient, models.RecipientSkipped, err)
+		return 0, false
+	}
+
+	resp, err := client.sendText(recipient.PhoneNumber, text, stop)
+	var rateErr *RateLimitError
+	switch {
+	case err == nil:
+		sentAt := resp.Timestamp
+		if sentAt.IsZero() {
+			sentAt = time.Now()
+		}
+		client.recordSent(&models.Message{PhoneNumber: recipient.PhoneNumber, Type: messageTypeText, Body: text}, resp)
+		if err := m.DB.MarkRecipientSent(recipient.ID, resp.ID, sentAt); err != nil {
+			log.Printf("[Campaign %s] Erro ao registrar envio para %s: %v", campaign.ID, recipient.PhoneNumber, err)
+			return outboxPollInterval, false
+		}
+
+	case errors.Is(err, ErrNotConnected), errors.Is(err, errShuttingDown):
+		// Não chegou a ser tentado; o destinatário continua pendente
+		return outboxPollInterval, false
+
+	case errors.As(err, &rateErr):
+		log.Printf("[Campaign %s] %v", campaign.ID, rateErr)
+		return rateErr.RetryAfter, false
+
+	case errors.Is(err, ErrInvalidPhone):
+		m.finishRecipient(campaign.ID, recipient, models.RecipientSkipped, err)
+
+	default:
+		m.finishRecipient(campaign.ID, recipient, models.RecipientFailed, err)
+	}
+	return 0, false
+}
+
+// finishRecipient encerra um destinatário sem envio, registrando o motivo
+func (m *Manager) finishRecipient(campaignID string, recipient *models.CampaignRecipient, status string, reason error) {
+	log.Printf("[Campaign %s] Destinatário %s %s: %v", campaignID, recipient.PhoneNumber, recipientStatusLabel(status), reason)
+	if err := m.DB.MarkRecipient(recipient.ID, status, reason.Error()); err != nil {
+		log.Printf("[Campaign %s] Erro ao atualizar destinatário %s: %v", campaignID, recipient.PhoneNumber, err)
+	}
+}
+
+func recipientStatusLabel(status string) string {
+	if status == models.RecipientSkipped {
+		return "ignorado"
+	}
+	return "com falha"
+}
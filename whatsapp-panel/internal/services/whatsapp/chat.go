@@ -0,0 +1,66 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mau.fi/whatsmeow/types"
+
+	"whatsapp-panel/internal/models"
+)
+
+// ErrInvalidChat indica um identificador de conversa inválido
+var ErrInvalidChat = errors.New("conversa inválida")
+
+// ListChats retorna as conversas da sessão, da mais recente para a mais
+// antiga, com o nome do contato quando ele é conhecido
+func (m *Manager) ListChats(tenantID, sessionID string, limit, offset int) ([]models.Chat, error) {
+	if _, err := m.DB.GetSession(tenantID, sessionID); err != nil {
+		return nil, err
+	}
+	chats, err := m.DB.ListChats(tenantID, sessionID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var names map[types.JID]string
+	if client, exists := m.GetClient(tenantID, sessionID); exists && client.WAClient != nil && len(chats) > 0 {
+		names = client.contactNames()
+	}
+	for i := range chats {
+		chat := &chats[i]
+		if jid, err := types.ParseJID(chat.JID); err == nil && jid.Server == types.DefaultUserServer {
+			chat.Name = names[jid]
+		}
+		if chat.Name == "" && chat.LastMessage.Direction == models.MessageInbound {
+			chat.Name = chat.LastMessage.SenderName
+		}
+	}
+	return chats, nil
+}
+
+// ListChatMessages retorna as mensagens de uma conversa da sessão, da mais
+// recente para a mais antiga. A conversa pode ser informada pelo JID ou, para
+// contatos, apenas pelo número.
+func (m *Manager) ListChatMessages(tenantID, sessionID, chat string, limit, offset int) ([]models.Message, error) {
+	jid, err := chatJID(chat)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.DB.GetSession(tenantID, sessionID); err != nil {
+		return nil, err
+	}
+	return m.DB.ListChatMessages(tenantID, sessionID, jid.String(), limit, offset)
+}
+
+// chatJID interpreta o identificador de uma conversa
+func chatJID(chat string) (types.JID, error) {
+	if phoneNumberPattern.MatchString(chat) {
+		return recipientJID(chat)
+	}
+	jid, err := types.ParseJID(chat)
+	if err != nil || jid.User == "" || jid.Server == "" {
+		return types.JID{}, fmt.Errorf("%w: informe o JID da conversa ou o número do contato", ErrInvalidChat)
+	}
+	return jid.ToNonAD(), nil
+}
@@ -3,38 +3,88 @@ package whatsapp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"go.mau.fi/whatsmeow"
 	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/store"
 	"go.mau.fi/whatsmeow/store/sqlstore"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 	waLog "go.mau.fi/whatsmeow/util/log"
 	"google.golang.org/protobuf/proto"
 
+	"whatsapp-panel/internal/config"
+	"whatsapp-panel/internal/models"
 	"whatsapp-panel/internal/storage"
 )
 
 type Client struct {
-	WAClient  *whatsmeow.Client
+	WAClient  Transport
 	ID        string
+	TenantID  string
 	Store     *sqlstore.Container
 	DB        *storage.Database
 	Mutex     sync.Mutex
 	Connected bool
+
+	// Events persiste os eventos da sessão no banco do painel
+	Events *EventHandler
+
+	status          string
+	statusChangedAt time.Time
+	proxy           string
+	limiter         *sendLimiter
+	reconnect       reconnectState
+	health          healthState
+
+	// Envios em andamento, drenados pelo Manager.Shutdown
+	sends    sync.WaitGroup
+	inFlight int64
+	closing  bool
 }
 
 type Manager struct {
 	Clients map[string]*Client
 	DB      *storage.Database
 	Mutex   sync.Mutex
+	// NewTransport cria o transporte de cada cliente; por padrão usa o whatsmeow
+	NewTransport TransportFactory
+	// Reconnect é a política de reconexão automática aplicada aos novos clientes
+	Reconnect ReconnectPolicy
+	// Watchdog é a política de verificação de saúde aplicada aos novos clientes
+	Watchdog WatchdogPolicy
+	// Sessions guarda os arquivos whatsmeow de cada sessão
+	Sessions *SessionStore
+
+	// Reconciler é a política do reconciliador de sessões órfãs
+	Reconciler ReconcilePolicy
+	// Media limita o recebimento de mídias enviadas pela API
+	Media MediaPolicy
+	// Outbox é a política de novas tentativas da fila de envio
+	Outbox OutboxPolicy
+	// RateLimits são os limites de envio das sessões sem limites próprios
+	RateLimits models.RateLimits
+	// Campaigns limita as campanhas de envio em massa
+	Campaigns CampaignPolicy
+
+	// createMu serializa a verificação de cota e a criação de sessões
+	createMu sync.Mutex
+
+	reconcileMu   sync.Mutex
+	reconcileStop chan struct{}
+
+	outbox    outboxWorkers
+	campaigns campaignWorkers
+	schedules scheduler
 }
 
 // Configuração global para limites de conexão
@@ -43,61 +93,255 @@ var (
 	CleanupTimeout = 2 * time.Minute
 )
 
-func NewManager(db *storage.Database) *Manager {
-	return &Manager{
-		Clients: make(map[string]*Client),
-		DB:      db,
+// legacySessionsDir é o diretório relativo usado antes de config.SessionsDir
+const legacySessionsDir = "storage/sessions"
+
+// NewManager cria o gerenciador a partir da configuração: diretório de
+// sessões, política de reconexão e transporte (real ou simulado)
+func NewManager(cfg *config.Config, db *storage.Database) (*Manager, error) {
+	sessions, err := NewSessionStore(cfg.SessionsDir)
+	if err != nil {
+		return nil, err
+	}
+	migrateLegacySessions(sessions)
+
+	key, err := LoadStoreKey(cfg.SessionKey, cfg.SessionKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	sessions.Key = key
+	if key == nil {
+		log.Println("[Manager] Aviso: SESSION_KEY não configurada, arquivos de sessão gravados sem criptografia")
 	}
+
+	m := &Manager{
+		Clients:      make(map[string]*Client),
+		DB:           db,
+		NewTransport: NewWhatsmeowTransport,
+		Reconnect: ReconnectPolicy{
+			InitialDelay: cfg.ReconnectInitialDelay,
+			MaxDelay:     cfg.ReconnectMaxDelay,
+			Multiplier:   cfg.ReconnectMultiplier,
+			Jitter:       cfg.ReconnectJitter,
+			MaxAttempts:  cfg.ReconnectMaxAttempts,
+		},
+		Watchdog: WatchdogPolicy{
+			Interval:         cfg.WatchdogInterval,
+			InboundTimeout:   cfg.WatchdogInboundTimeout,
+			KeepAliveMaxFail: cfg.WatchdogKeepAliveMaxFail,
+		},
+		Reconciler: ReconcilePolicy{
+			Interval: cfg.ReconcileInterval,
+			Grace:    cfg.ReconcileGrace,
+			DryRun:   cfg.ReconcileDryRun,
+		},
+		Media: MediaPolicy{
+			MaxSize:          cfg.MediaMaxSize,
+			FetchTimeout:     cfg.MediaFetchTimeout,
+			AllowPrivateURLs: cfg.MediaAllowPrivateURLs,
+		},
+		Outbox: OutboxPolicy{
+			MaxAttempts:   cfg.OutboxMaxAttempts,
+			RetryDelay:    cfg.OutboxRetryDelay,
+			RetryMaxDelay: cfg.OutboxRetryMaxDelay,
+		},
+		RateLimits: models.RateLimits{
+			PerMinute:  cfg.RateLimitPerMinute,
+			PerHour:    cfg.RateLimitPerHour,
+			PerDay:     cfg.RateLimitPerDay,
+			MinDelayMS: int(cfg.SendDelayMin / time.Millisecond),
+			MaxDelayMS: int(cfg.SendDelayMax / time.Millisecond),
+		},
+		Campaigns: CampaignPolicy{
+			MaxRecipients: cfg.CampaignMaxRecipients,
+		},
+		Sessions: sessions,
+	}
+	if err := ValidateRateLimits(m.RateLimits); err != nil {
+		return nil, fmt.Errorf("RATE_LIMIT_*/SEND_DELAY_*: %v", err)
+	}
+	if cfg.FakeWhatsApp {
+		log.Println("[Manager] Modo offline: usando transporte WhatsApp simulado")
+		m.NewTransport = FakeTransportFactory(5 * time.Second)
+	}
+	return m, nil
 }
 
-func (m *Manager) NewClient() (*Client, error) {
-	clientID := uuid.New().String()
-	storeDir := "storage/sessions"
-	if err := os.MkdirAll(storeDir, 0755); err != nil {
-		return nil, fmt.Errorf("erro ao criar diretório de sessões: %v", err)
+// migrateLegacySessions move para o diretório configurado as sessões que
+// ainda estão no diretório relativo antigo, que não é mais lido. Sessões em
+// uso por outro processo ou que já existem no destino ficam onde estão.
+func migrateLegacySessions(sessions *SessionStore) {
+	legacyDir, err := filepath.Abs(legacySessionsDir)
+	if err != nil {
+		return
+	}
+	currentDir, err := filepath.Abs(sessions.Dir)
+	if err != nil || legacyDir == currentDir {
+		return
+	}
+	legacy := &SessionStore{Dir: legacyDir}
+	files, _ := legacy.Files()
+	moved := 0
+	for _, file := range files {
+		sessionID := legacy.SessionID(file)
+		owner, err := legacy.Owner(sessionID)
+		if err != nil {
+			log.Printf("[Manager] ⚠️ Sessão %s em %s não foi movida: %v", sessionID, legacyDir, err)
+			continue
+		}
+		if owner != nil {
+			log.Printf("[Manager] ⚠️ Sessão %s em %s está em uso por %s; não foi movida", sessionID, legacyDir, owner)
+			continue
+		}
+		if _, err := os.Stat(sessions.Path(sessionID)); err == nil {
+			log.Printf("[Manager] ⚠️ Sessão %s existe em %s e em %s; mantida a de %s", sessionID, legacyDir, currentDir, currentDir)
+			continue
+		}
+		if err := moveFile(legacy.Path(sessionID), sessions.Path(sessionID)); err != nil {
+			log.Printf("[Manager] ❌ Erro ao mover sessão %s de %s: %v", sessionID, legacyDir, err)
+			continue
+		}
+		// Arquivos auxiliares do SQLite acompanham o banco
+		for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+			src := legacy.Path(sessionID) + suffix
+			if _, err := os.Stat(src); err != nil {
+				continue
+			}
+			if err := moveFile(src, sessions.Path(sessionID)+suffix); err != nil {
+				log.Printf("[Manager] ⚠️ Erro ao mover %s: %v", src, err)
+			}
+		}
+		os.Remove(legacy.lockPath(sessionID))
+		log.Printf("[Manager] 📦 Sessão %s movida de %s para %s", sessionID, legacyDir, currentDir)
+		moved++
+	}
+	if moved > 0 {
+		log.Printf("[Manager] %d sessões migradas de %s para %s", moved, legacyDir, currentDir)
+	}
+}
+
+// moveFile renomeia o arquivo, copiando-o quando origem e destino estão em
+// sistemas de arquivos diferentes
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(dst, data); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// NewClient cria uma sessão aguardando pareamento no tenant informado,
+// respeitando a cota de sessões do tenant (ver CheckQuota)
+func (m *Manager) NewClient(tenantID string) (*Client, error) {
+	return m.NewClientWithProxy(tenantID, "")
+}
+
+// NewClientWithProxy cria uma sessão como NewClient, conectando pelo proxy
+// de saída informado (http, https ou socks5; "" = conexão direta)
+func (m *Manager) NewClientWithProxy(tenantID, proxyURL string) (*Client, error) {
+	proxyURL, err := NormalizeProxyURL(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	m.createMu.Lock()
+	defer m.createMu.Unlock()
+
+	if err := m.CheckQuota(tenantID); err != nil {
+		return nil, err
 	}
 
-	dbPath := filepath.Join(storeDir, clientID+".db")
+	clientID := uuid.New().String()
 
 	// Usar logger mais detalhado
 	logger := waLog.Stdout("whatsmeow", "DEBUG", true)
 
 	// Criar store com timeout mais longo e flags adicionais
-	store, err := sqlstore.New("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=30000&cache=shared", dbPath), logger)
+	container, err := m.Sessions.Open(clientID, logger)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao criar store: %v", err)
+		return nil, err
+	}
+
+	device := container.NewDevice()
+	waCli := m.newClient(clientID, tenantID, container, device, logger)
+	if err := waCli.setProxy(proxyURL); err != nil {
+		m.RemoveClient(clientID)
+		m.Sessions.Remove(clientID)
+		return nil, err
 	}
 
-	device := store.NewDevice()
+	// Configurar limpeza automática com timeout global
+	m.CleanupClientAfterTimeout(clientID, CleanupTimeout)
+	log.Printf("[Manager] Cliente %s criado com limpeza automática configurada", clientID)
+
+	return waCli, nil
+}
 
+// newClient cria o cliente whatsmeow para o dispositivo, configura os handlers
+// de eventos e registra o cliente no gerenciador
+func (m *Manager) newClient(clientID, tenantID string, container *sqlstore.Container, device *store.Device, logger waLog.Logger) *Client {
 	// Configurar cliente WhatsApp
-	client := whatsmeow.NewClient(device, logger)
+	client := m.NewTransport(device, logger)
 
-	// Verificar se está configurado corretamente
-	if client != nil && client.Store != nil {
-		log.Printf("Store configurado: %T", client.Store)
+	// Dispositivos já pareados começam como "paired"; novos aguardam o QR
+	initialStatus := models.StatusPending
+	if device.ID != nil {
+		initialStatus = models.StatusPaired
 	}
 
 	waCli := &Client{
-		WAClient:  client,
-		ID:        clientID,
-		Store:     store,
-		DB:        m.DB,
-		Connected: false,
+		WAClient:        client,
+		ID:              clientID,
+		TenantID:        tenantID,
+		Store:           container,
+		DB:              m.DB,
+		Connected:       false,
+		status:          initialStatus,
+		statusChangedAt: time.Now(),
 	}
+	waCli.reconnect.policy = m.Reconnect
+	waCli.limiter = m.newClientLimiter(clientID)
+
+	// Sinais de saúde (keepalive, último evento recebido) para o watchdog
+	client.AddEventHandler(waCli.observeEvent)
+
+	// Persistência dos eventos: registrado antes da máquina de estados para que
+	// a linha da sessão já exista quando o status de pareamento for gravado
+	waCli.Events = NewEventHandler(m.DB, clientID)
+	waCli.Events.TenantID = tenantID
+	waCli.Events.Transport = client
+	client.AddEventHandler(waCli.Events.Handle)
 
 	// Configurar handlers de eventos
 	client.AddEventHandler(func(evt interface{}) {
 		switch e := evt.(type) {
+		case *events.PairSuccess:
+			log.Printf("[Client %s] 🔗 Pareamento concluído: %s", clientID, e.ID.String())
+			waCli.setStatus(models.StatusPaired, "pareamento concluído")
+			waCli.persistProxy()
+			waCli.persistRateLimits()
 		case *events.Connected:
 			log.Printf("[Client %s] ✅ Cliente conectado com sucesso", clientID)
-			waCli.setConnected(true)
+			waCli.setStatus(models.StatusConnected, "conexão estabelecida")
 		case *events.Disconnected:
 			log.Printf("[Client %s] ⚠️ Cliente desconectado", clientID)
-			waCli.setConnected(false)
+			waCli.setStatus(models.StatusDisconnected, "conexão perdida")
+			waCli.startReconnect("conexão perdida")
+		case *events.TemporaryBan:
+			log.Printf("[Client %s] ⛔ Número banido temporariamente: %s", clientID, e.String())
+			waCli.stopReconnect("banido: "+e.String(), true)
+			waCli.setStatus(models.StatusDisconnected, "banido: "+e.String())
 		case *events.LoggedOut:
 			log.Printf("[Client %s] ❌ Cliente deslogado", clientID)
-			waCli.setConnected(false)
+			waCli.stopReconnect("sessão deslogada", true)
+			waCli.stopWatchdog()
+			waCli.setStatus(models.StatusLoggedOut, fmt.Sprintf("deslogado pelo WhatsApp (%s)", e.Reason.String()))
 			go m.RemoveClient(clientID)
 		case *events.QR:
 			log.Printf("[Client %s] 📱 Evento QR recebido", clientID)
@@ -112,25 +356,182 @@ func (m *Manager) NewClient() (*Client, error) {
 	m.Clients[clientID] = waCli
 	m.Mutex.Unlock()
 
-	// Configurar limpeza automática com timeout global
-	m.CleanupClientAfterTimeout(clientID, CleanupTimeout)
-	log.Printf("[Manager] Cliente %s criado com limpeza automática configurada", clientID)
+	waCli.startWatchdog(m.Watchdog)
+
+	return waCli
+}
+
+// RestoreFailure descreve um arquivo de sessão que não pôde ser restaurado
+type RestoreFailure struct {
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+}
+
+// RestoreReport resume o resultado da restauração de sessões na inicialização
+type RestoreReport struct {
+	Restored []string         `json:"restored"`
+	Failed   []RestoreFailure `json:"failed"`
+}
+
+// RestoreSessions percorre o diretório de sessões e recria um Client para cada
+// dispositivo já pareado, reconectando-o em segundo plano e religando-o à sua
+// linha em whatsapp_sessions. Arquivos que não puderem ser restaurados são
+// apenas reportados, nunca removidos.
+func (m *Manager) RestoreSessions() (*RestoreReport, error) {
+	report := &RestoreReport{}
+
+	files, err := m.Sessions.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	logger := waLog.Stdout("whatsmeow", "DEBUG", true)
+
+	for _, file := range files {
+		clientID := m.Sessions.SessionID(file)
+		if _, err := uuid.Parse(clientID); err != nil {
+			report.Failed = append(report.Failed, RestoreFailure{File: file, Reason: "nome de arquivo não é um ID de sessão"})
+			continue
+		}
+
+		m.Mutex.Lock()
+		_, exists := m.Clients[clientID]
+		m.Mutex.Unlock()
+		if exists {
+			continue
+		}
+
+		waCli, err := m.restoreClient(clientID, logger)
+		if err != nil {
+			log.Printf("[Restore] Sessão %s não restaurada: %v", clientID, err)
+			report.Failed = append(report.Failed, RestoreFailure{File: file, Reason: err.Error()})
+			continue
+		}
+
+		report.Restored = append(report.Restored, clientID)
+		go m.reconnectRestored(waCli)
+	}
+
+	return report, nil
+}
+
+// restoreClient abre o store de uma sessão existente e recria o Client se o
+// dispositivo tiver um ID armazenado
+func (m *Manager) restoreClient(clientID string, logger waLog.Logger) (*Client, error) {
+	container, err := m.Sessions.Open(clientID, logger)
+	if err != nil {
+		return nil, err
+	}
 
+	device, err := container.GetFirstDevice()
+	if err != nil {
+		m.Sessions.Close(clientID, container)
+		return nil, fmt.Errorf("erro ao ler dispositivo: %v", err)
+	}
+	if device == nil || device.ID == nil {
+		m.Sessions.Close(clientID, container)
+		return nil, fmt.Errorf("store não contém dispositivo pareado")
+	}
+
+	// Arquivos sem linha no banco (sessão excluída, ou pareada com outro banco)
+	// não são restaurados nem apagados: o dono decide o que fazer com eles
+	tenantID, err := m.DB.GetSessionTenant(clientID)
+	if err == storage.ErrSessionNotFound {
+		m.Sessions.Close(clientID, container)
+		return nil, fmt.Errorf("dispositivo %s sem sessão cadastrada no banco; importe-o ou apague o arquivo", device.ID.ToNonAD())
+	} else if err != nil {
+		m.Sessions.Close(clientID, container)
+		return nil, fmt.Errorf("erro ao ler workspace da sessão: %v", err)
+	}
+
+	proxyURL, err := m.DB.GetSessionProxy(clientID)
+	if err != nil && err != storage.ErrSessionNotFound {
+		m.Sessions.Close(clientID, container)
+		return nil, fmt.Errorf("erro ao ler proxy da sessão: %v", err)
+	}
+
+	log.Printf("[Restore] Restaurando sessão %s (%s)", clientID, device.ID.String())
+	waCli := m.newClient(clientID, tenantID, container, device, logger)
+	if err := waCli.setProxy(proxyURL); err != nil {
+		m.RemoveClient(clientID)
+		return nil, err
+	}
 	return waCli, nil
 }
 
+// reconnectRestored reconecta uma sessão restaurada e atualiza sua linha no banco
+func (m *Manager) reconnectRestored(client *Client) {
+	jid := *client.WAClient.DeviceID()
+
+	if err := m.DB.LinkSession(client.ID, jid.ToNonAD().String(), jid.User, client.Status()); err != nil {
+		// A linha foi conferida em restoreClient; sem ela a sessão não reconecta
+		if err == storage.ErrSessionNotFound {
+			log.Printf("[Restore] Sessão %s excluída durante a restauração", client.ID)
+			m.RemoveClient(client.ID)
+			return
+		}
+		log.Printf("[Restore] Erro ao religar sessão %s ao banco: %v", client.ID, err)
+	}
+
+	if err := client.Connect(); err != nil {
+		log.Printf("[Restore] Erro ao reconectar sessão %s: %v", client.ID, err)
+		client.startReconnect("falha ao reconectar sessão restaurada")
+	}
+}
+
 // Adicionar o método RemoveClient para gerenciar a remoção de clientes
 func (m *Manager) RemoveClient(clientID string) {
 	m.Mutex.Lock()
 	defer m.Mutex.Unlock()
 
 	if client, exists := m.Clients[clientID]; exists {
+		client.stopWatchdog()
 		client.Disconnect()
+		if client.Store != nil {
+			if err := m.Sessions.Close(clientID, client.Store); err != nil {
+				log.Printf("[Manager] Erro ao fechar store da sessão %s: %v", clientID, err)
+			}
+		}
 		delete(m.Clients, clientID)
 		log.Printf("[Manager] Cliente %s removido do gerenciador", clientID)
 	}
 }
 
+// DeleteSession exclui uma sessão do tenant: desconecta o aparelho do
+// WhatsApp (logout), apaga o arquivo do dispositivo e só então a linha no
+// banco, para que a sessão não volte a ser restaurada no próximo início
+func (m *Manager) DeleteSession(tenantID, sessionID string) error {
+	client, live := m.GetClient(tenantID, sessionID)
+	if !live {
+		owner, err := m.DB.GetSessionTenant(sessionID)
+		if err != nil {
+			return err
+		}
+		if owner != tenantID {
+			return storage.ErrSessionNotFound
+		}
+	}
+
+	if live {
+		if client.WAClient.IsConnected() && client.WAClient.DeviceID() != nil {
+			if err := client.WAClient.Logout(); err != nil {
+				log.Printf("[Manager] Erro ao deslogar sessão %s (o arquivo será apagado mesmo assim): %v", sessionID, err)
+			}
+		}
+		m.RemoveClient(sessionID)
+	}
+	if err := m.removeSessionFile(sessionID); err != nil {
+		return fmt.Errorf("erro ao apagar arquivo da sessão: %w", err)
+	}
+
+	err := m.DB.DeleteSession(tenantID, sessionID)
+	if err == storage.ErrSessionNotFound && live {
+		// Sessão ainda pendente de pareamento, sem linha no banco
+		return nil
+	}
+	return err
+}
+
 // CleanupClientAfterTimeout configura um temporizador para remover o cliente
 // se ele não se conectar dentro de um determinado período de tempo.
 // Também remove o arquivo de banco de dados associado.
@@ -151,16 +552,14 @@ func (m *Manager) CleanupClientAfterTimeout(clientID string, timeout time.Durati
 			// Remover cliente do gerenciador
 			m.RemoveClient(clientID)
 
-			// Remover arquivo de banco de dados
-			storeDir := "storage/sessions"
-			dbPath := filepath.Join(storeDir, clientID+".db")
-
 			// Forçar a desconexão do cliente do WhatsApp
 			if client != nil && client.WAClient != nil {
 				client.WAClient.Disconnect()
 			}
 
-			if err := os.Remove(dbPath); err != nil {
+			// Remover arquivo de banco de dados
+			dbPath := m.Sessions.Path(clientID)
+			if err := m.Sessions.Remove(clientID); err != nil {
 				log.Printf("[Cleanup] Erro ao excluir arquivo de sessão %s: %v", dbPath, err)
 			} else {
 				log.Printf("[Cleanup] Arquivo de sessão removido: %s", dbPath)
@@ -186,6 +585,7 @@ func (c *Client) Connect() error {
 	err := c.WAClient.Connect()
 	if err != nil {
 		log.Printf("[Client %s] Erro ao conectar: %v", c.ID, err)
+		c.markConnectFailed(err.Error())
 		return fmt.Errorf("erro ao conectar: %v", err)
 	}
 
@@ -201,9 +601,10 @@ func (c *Client) Connect() error {
 
 	if connected {
 		log.Printf("[Client %s] Conexão estabelecida com sucesso", c.ID)
-		c.setConnected(true)
+		c.setStatus(models.StatusConnected, "conexão estabelecida")
 	} else {
 		log.Printf("[Client %s] Timeout ao estabelecer conexão", c.ID)
+		c.markConnectFailed("timeout ao estabelecer conexão")
 		return fmt.Errorf("timeout ao estabelecer conexão")
 	}
 
@@ -212,33 +613,32 @@ func (c *Client) Connect() error {
 
 // Melhorar o método de desconexão
 func (c *Client) Disconnect() {
+	c.disconnect("desconectado pelo painel")
+}
+
+// disconnect encerra a conexão sem reconexão automática, registrando o motivo
+func (c *Client) disconnect(reason string) {
 	c.Mutex.Lock()
 	wasConnected := c.Connected
 	c.Mutex.Unlock()
 
+	// Uma desconexão pedida pelo painel não deve ser revertida pela reconexão automática
+	c.stopReconnect(reason, false)
+
 	if wasConnected {
 		log.Printf("[Client %s] Desconectando cliente que estava ativo", c.ID)
 		c.WAClient.Disconnect()
-		c.setConnected(false)
+		c.setStatus(models.StatusDisconnected, reason)
 	} else {
 		log.Printf("[Client %s] Tentativa de desconexão em cliente já desconectado", c.ID)
 	}
 }
 
-// Melhorar o método setConnected para notificar quando o status mudar
-func (c *Client) setConnected(status bool) {
-	c.Mutex.Lock()
-	oldStatus := c.Connected
-	c.Connected = status
-	c.Mutex.Unlock()
-
-	// Se houve mudança de status, registrar no log
-	if oldStatus != status {
-		if status {
-			log.Printf("[Client %s] Status mudou para CONECTADO", c.ID)
-		} else {
-			log.Printf("[Client %s] Status mudou para DESCONECTADO", c.ID)
-		}
+// markConnectFailed registra a falha de conexão de uma sessão já pareada.
+// Sessões pendentes continuam aguardando o pareamento.
+func (c *Client) markConnectFailed(reason string) {
+	if c.Status() != models.StatusPending {
+		c.setStatus(models.StatusDisconnected, reason)
 	}
 }
 
@@ -287,26 +687,137 @@ func (c *Client) GetQRChannel(ctx context.Context) (<-chan string, error) {
 	return qrChan, nil
 }
 
+// PairPhone inicia o pareamento por código: conecta o cliente, aguarda a
+// conexão de login e pede ao WhatsApp um código de 8 caracteres para o número
+// informado. O canal de login fica ativo em segundo plano até o pareamento
+// terminar ou o CleanupTimeout expirar.
+func (c *Client) PairPhone(phoneNumber string) (string, error) {
+	if err := ValidatePhoneNumber(phoneNumber); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), CleanupTimeout)
+
+	qrChan, err := c.WAClient.GetQRChannel(ctx)
+	if err != nil {
+		cancel()
+		return "", fmt.Errorf("erro ao preparar pareamento: %v", err)
+	}
+
+	if err := c.WAClient.Connect(); err != nil {
+		cancel()
+		return "", fmt.Errorf("erro ao conectar: %v", err)
+	}
+
+	// O primeiro item do canal indica que o websocket de login está pronto
+	select {
+	case item, ok := <-qrChan:
+		if !ok || item.Event != whatsmeow.QRChannelEventCode {
+			cancel()
+			return "", fmt.Errorf("conexão de login encerrada: %s", item.Event)
+		}
+	case <-ctx.Done():
+		cancel()
+		return "", fmt.Errorf("timeout aguardando conexão de login")
+	}
+
+	code, err := c.WAClient.PairPhone(phoneNumber, true, whatsmeow.PairClientChrome, "Chrome (Linux)")
+	if err != nil {
+		cancel()
+		c.WAClient.Disconnect()
+		return "", fmt.Errorf("erro ao gerar código de pareamento: %v", err)
+	}
+
+	log.Printf("[Client %s] Código de pareamento gerado para %s", c.ID, phoneNumber)
+
+	// Consumir o canal até o fim para manter o login ativo
+	go func() {
+		defer cancel()
+		for item := range qrChan {
+			if item.Event != whatsmeow.QRChannelEventCode {
+				log.Printf("[Client %s] Pareamento por código finalizado: %s", c.ID, item.Event)
+			}
+		}
+	}()
+
+	return code, nil
+}
+
+var (
+	// ErrInvalidPhone indica um número de telefone fora do formato aceito
+	ErrInvalidPhone = errors.New("número de telefone inválido")
+	// ErrNotConnected é retornado por envios com o cliente desconectado
+	ErrNotConnected = errors.New("cliente não está conectado")
+)
+
+// phoneNumberPattern aceita o número completo, só com dígitos (país, área e número)
+var phoneNumberPattern = regexp.MustCompile(`^[0-9]{8,15}$`)
+
+// ValidatePhoneNumber confere se o número está no formato aceito pelos envios
+// e pelo pareamento por código: só dígitos, com códigos de país e área
+func ValidatePhoneNumber(phoneNumber string) error {
+	_, err := recipientJID(phoneNumber)
+	return err
+}
+
+// recipientJID converte um número de telefone para o formato JID (ID do WhatsApp)
+func recipientJID(phoneNumber string) (types.JID, error) {
+	if !phoneNumberPattern.MatchString(phoneNumber) {
+		return types.JID{}, fmt.Errorf("%w: use apenas dígitos, com códigos de país e área", ErrInvalidPhone)
+	}
+	recipient, err := types.ParseJID(phoneNumber + "@s.whatsapp.net")
+	if err != nil {
+		return types.JID{}, fmt.Errorf("%w: %v", ErrInvalidPhone, err)
+	}
+	return recipient, nil
+}
+
 // SendTextMessage envia uma mensagem de texto para um número de telefone
 func (c *Client) SendTextMessage(phoneNumber, message string) error {
+	resp, err := c.sendText(phoneNumber, message, nil)
+	if err != nil {
+		return err
+	}
+	c.recordSent(&models.Message{PhoneNumber: phoneNumber, Type: messageTypeText, Body: message}, resp)
+	return nil
+}
+
+// sendText envia a mensagem de texto e retorna a resposta do WhatsApp (ID e
+// horário). stop é repassado a acquireSendSlot: nil recusa o envio em vez de
+// aguardar o atraso entre envios.
+func (c *Client) sendText(phoneNumber, message string, stop <-chan struct{}) (whatsmeow.SendResponse, error) {
 	if !c.Connected {
-		return fmt.Errorf("cliente não está conectado")
+		return whatsmeow.SendResponse{}, ErrNotConnected
 	}
 
-	// Converter número de telefone para formato JID (ID do WhatsApp)
-	recipient, err := types.ParseJID(phoneNumber + "@s.whatsapp.net")
+	if err := c.beginSend(); err != nil {
+		return whatsmeow.SendResponse{}, err
+	}
+	defer c.endSend()
+
+	recipient, err := recipientJID(phoneNumber)
 	if err != nil {
-		return fmt.Errorf("número de telefone inválido: %v", err)
+		return whatsmeow.SendResponse{}, err
+	}
+	slot, err := c.acquireSendSlot(stop)
+	if err != nil {
+		return whatsmeow.SendResponse{}, err
+	}
+	// A sessão pode ter caído durante a espera
+	if !c.Connected {
+		c.limiter.release(slot)
+		return whatsmeow.SendResponse{}, ErrNotConnected
 	}
 
 	// Enviar mensagem
-	_, err = c.WAClient.SendMessage(context.Background(), recipient, &waProto.Message{
+	resp, err := c.WAClient.SendMessage(context.Background(), recipient, &waProto.Message{
 		Conversation: proto.String(message),
 	})
 
 	if err != nil {
-		return fmt.Errorf("erro ao enviar mensagem: %v", err)
+		c.limiter.release(slot)
+		return whatsmeow.SendResponse{}, fmt.Errorf("erro ao enviar mensagem: %v", err)
 	}
 
-	return nil
+	return resp, nil
 }
@@ -0,0 +1,89 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNextMonthEnds(t *testing.T) {
+	tests := []struct {
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		// Meses sem dia 31 são pulados
+		{"0 0 31 * *", time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 5, 31, 0, 0, 0, 0, time.UTC)},
+		// 29 de fevereiro só em ano bissexto
+		{"0 12 29 2 *", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+		// Virada do ano
+		{"30 23 * * *", time.Date(2025, 12, 31, 23, 30, 0, 0, time.UTC), time.Date(2026, 1, 1, 23, 30, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 12, 15, 8, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Dia do mês ou dia da semana: 1º de junho de 2025 é domingo
+		{"0 9 15 * 1", time.Date(2025, 5, 31, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+		}
+		if got := schedule.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("Next(%q, %s) = %s, quer %s", tt.expr, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestCronNextNeverMatches(t *testing.T) {
+	schedule, err := ParseCron("0 0 31 4 *")
+	if err != nil {
+		t.Fatalf("ParseCron: %v", err)
+	}
+	if got := schedule.Next(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next = %s, quer instante zero", got)
+	}
+}
+
+func TestCronNextAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("fuso indisponível: %v", err)
+	}
+
+	daily, _ := ParseCron("0 9 * * *")
+	// Início do horário de verão (9 de março de 2025, 02:00 -> 03:00): o
+	// horário local se mantém, e o intervalo real é de 23h
+	got := daily.Next(time.Date(2025, 3, 8, 9, 0, 0, 0, loc))
+	if want := time.Date(2025, 3, 9, 9, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Next no início do horário de verão = %s, quer %s", got, want)
+	}
+	if d := got.Sub(time.Date(2025, 3, 8, 9, 0, 0, 0, loc)); d != 23*time.Hour {
+		t.Errorf("intervalo = %s, quer 23h", d)
+	}
+	// Fim do horário de verão (2 de novembro de 2025): intervalo de 25h
+	got = daily.Next(time.Date(2025, 11, 1, 9, 0, 0, 0, loc))
+	if want := time.Date(2025, 11, 2, 9, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Next no fim do horário de verão = %s, quer %s", got, want)
+	}
+
+	// A cada hora, durante a hora que se repete no fim do horário de verão,
+	// o próximo horário é sempre posterior ao anterior
+	hourly, _ := ParseCron("0 * * * *")
+	prev := time.Date(2025, 11, 1, 23, 0, 0, 0, loc)
+	for i := 0; i < 6; i++ {
+		next := hourly.Next(prev)
+		if !next.After(prev) || next.Minute() != 0 {
+			t.Fatalf("Next(%s) = %s", prev, next)
+		}
+		prev = next
+	}
+	if want := time.Date(2025, 11, 2, 4, 0, 0, 0, loc); !prev.Equal(want) {
+		t.Errorf("após 6 execuções = %s, quer %s", prev, want)
+	}
+
+	// 02:30 não existe no início do horário de verão; o próximo é o do dia seguinte
+	skipped, _ := ParseCron("30 2 * * *")
+	got = skipped.Next(time.Date(2025, 3, 8, 12, 0, 0, 0, loc))
+	if got.Hour() != 2 || got.Minute() != 30 || !got.After(time.Date(2025, 3, 9, 3, 0, 0, 0, loc)) {
+		t.Errorf("Next com horário inexistente = %s", got)
+	}
+}
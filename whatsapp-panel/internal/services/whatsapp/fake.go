@@ -0,0 +1,519 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/proto/waAdv"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+)
+
+// FakeSentMessage registra uma mensagem enviada através do FakeTransport
+type FakeSentMessage struct {
+	ID        types.MessageID
+	To        types.JID
+	Message   *waProto.Message
+	Timestamp time.Time
+}
+
+// FakeTransport é um Transport em memória controlado por script. Ele permite
+// rodar e testar o painel sem telefone e sem rede: os eventos (QR, PairSuccess,
+// Message, Receipt, LoggedOut) são emitidos pelos métodos Emit*.
+type FakeTransport struct {
+	// ConnectErr, se definido, é retornado por Connect
+	ConnectErr error
+	// SendErr, se definido, é retornado por SendMessage
+	SendErr error
+	// UploadErr, se definido, é retornado por Upload
+	UploadErr error
+	// QRInterval é o intervalo entre códigos emitidos no canal de QR
+	QRInterval time.Duration
+	// AutoPair, se maior que zero, simula a leitura do QR após esse intervalo
+	AutoPair time.Duration
+
+	device    *store.Device
+	mu        sync.Mutex
+	handlers  []fakeHandler
+	nextID    uint32
+	connected bool
+	pairPhone string
+	proxy     string
+	sent      []FakeSentMessage
+	contacts  map[types.JID]types.ContactInfo
+}
+
+type fakeHandler struct {
+	id uint32
+	fn whatsmeow.EventHandler
+}
+
+// NewFakeTransport cria um FakeTransport para o dispositivo
+func NewFakeTransport(device *store.Device) *FakeTransport {
+	return &FakeTransport{
+		QRInterval: 20 * time.Second,
+		device:     device,
+		contacts:   make(map[types.JID]types.ContactInfo),
+	}
+}
+
+// FakeTransportFactory retorna uma TransportFactory de FakeTransports que
+// pareiam sozinhos após autoPair (zero desativa o pareamento automático)
+func FakeTransportFactory(autoPair time.Duration) TransportFactory {
+	return func(device *store.Device, logger waLog.Logger) Transport {
+		t := NewFakeTransport(device)
+		t.AutoPair = autoPair
+		return t
+	}
+}
+
+func (t *FakeTransport) Connect() error {
+	t.mu.Lock()
+	if t.ConnectErr != nil {
+		err := t.ConnectErr
+		t.mu.Unlock()
+		return err
+	}
+	if t.connected {
+		t.mu.Unlock()
+		return whatsmeow.ErrAlreadyConnected
+	}
+	t.connected = true
+	paired := t.device.ID != nil
+	autoPair := t.AutoPair
+	t.mu.Unlock()
+
+	if paired {
+		t.Emit(&events.Connected{})
+		return nil
+	}
+
+	// Assim como o whatsmeow, o evento QR chega depois do Connect retornar
+	go t.EmitQR(fakeQRCodes(6)...)
+
+	if autoPair > 0 {
+		go func() {
+			time.Sleep(autoPair)
+			if t.IsConnected() && t.DeviceID() == nil {
+				t.EmitPairSuccess(t.pairingJID())
+			}
+		}()
+	}
+	return nil
+}
+
+func (t *FakeTransport) Disconnect() {
+	t.mu.Lock()
+	t.connected = false
+	t.mu.Unlock()
+}
+
+func (t *FakeTransport) IsConnected() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.connected
+}
+
+func (t *FakeTransport) IsLoggedIn() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.connected && t.device.ID != nil
+}
+
+func (t *FakeTransport) DeviceID() *types.JID {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.device.ID
+}
+
+// GetQRChannel reproduz o comportamento do canal de QR do whatsmeow: emite um
+// código por intervalo e termina com "success", "timeout" ou um erro
+func (t *FakeTransport) GetQRChannel(ctx context.Context) (<-chan whatsmeow.QRChannelItem, error) {
+	if t.IsConnected() {
+		return nil, whatsmeow.ErrQRAlreadyConnected
+	} else if t.DeviceID() != nil {
+		return nil, whatsmeow.ErrQRStoreContainsID
+	}
+
+	qrc := &fakeQRChannel{
+		transport: t,
+		ctx:       ctx,
+		output:    make(chan whatsmeow.QRChannelItem, 8),
+		stop:      make(chan struct{}),
+	}
+	qrc.handlerID = t.AddEventHandler(qrc.handleEvent)
+	return qrc.output, nil
+}
+
+// PairPhone retorna um código de pareamento fictício. Com AutoPair, o
+// pareamento automático usa o número informado.
+func (t *FakeTransport) PairPhone(phone string, showPushNotification bool, clientType whatsmeow.PairClientType, clientDisplayName string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.connected {
+		return "", whatsmeow.ErrNotConnected
+	}
+	phone = strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, phone)
+	if len(phone) <= 6 {
+		return "", fmt.Errorf("phone number too short")
+	}
+	t.pairPhone = phone
+
+	code := strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:8])
+	return code[:4] + "-" + code[4:], nil
+}
+
+// pairingJID retorna o JID usado no pareamento automático
+func (t *FakeTransport) pairingJID() types.JID {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pairPhone != "" {
+		return types.NewADJID(t.pairPhone, 0, 1)
+	}
+	return fakeJID()
+}
+
+func (t *FakeTransport) SendMessage(ctx context.Context, to types.JID, message *waProto.Message, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.connected {
+		return whatsmeow.SendResponse{}, whatsmeow.ErrNotConnected
+	}
+	if t.SendErr != nil {
+		return whatsmeow.SendResponse{}, t.SendErr
+	}
+
+	sent := FakeSentMessage{
+		ID:        fakeMessageID(),
+		To:        to,
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+	t.sent = append(t.sent, sent)
+	return whatsmeow.SendResponse{ID: sent.ID, Timestamp: sent.Timestamp}, nil
+}
+
+// Upload simula o envio de uma mídia: nada sai da memória, mas a resposta tem
+// os mesmos campos (hashes reais, chave e caminho fictícios) do whatsmeow
+func (t *FakeTransport) Upload(ctx context.Context, plaintext []byte, appInfo whatsmeow.MediaType) (whatsmeow.UploadResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.connected {
+		return whatsmeow.UploadResponse{}, whatsmeow.ErrNotConnected
+	}
+	if t.UploadErr != nil {
+		return whatsmeow.UploadResponse{}, t.UploadErr
+	}
+
+	mediaKey := make([]byte, 32)
+	rand.Read(mediaKey)
+	fileSHA256 := sha256.Sum256(plaintext)
+	encSHA256 := sha256.Sum256(append(mediaKey, plaintext...))
+	directPath := "/v/t62.7118-24/fake-" + hex.EncodeToString(fileSHA256[:8])
+	return whatsmeow.UploadResponse{
+		URL:           "https://mmg.whatsapp.net" + directPath,
+		DirectPath:    directPath,
+		MediaKey:      mediaKey,
+		FileEncSHA256: encSHA256[:],
+		FileSHA256:    fileSHA256[:],
+		FileLength:    uint64(len(plaintext)),
+	}, nil
+}
+
+func (t *FakeTransport) AddEventHandler(handler whatsmeow.EventHandler) uint32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	t.handlers = append(t.handlers, fakeHandler{id: t.nextID, fn: handler})
+	return t.nextID
+}
+
+func (t *FakeTransport) removeEventHandler(id uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, h := range t.handlers {
+		if h.id == id {
+			t.handlers = append(t.handlers[:i], t.handlers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *FakeTransport) SetProxyAddress(addr string, opts ...whatsmeow.SetProxyOptions) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.proxy = addr
+	return nil
+}
+
+// ProxyAddress retorna o proxy definido por SetProxyAddress
+func (t *FakeTransport) ProxyAddress() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.proxy
+}
+
+func (t *FakeTransport) GetAllContacts() (map[types.JID]types.ContactInfo, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	contacts := make(map[types.JID]types.ContactInfo, len(t.contacts))
+	for jid, info := range t.contacts {
+		contacts[jid] = info
+	}
+	return contacts, nil
+}
+
+func (t *FakeTransport) GetContact(jid types.JID) (types.ContactInfo, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	info, ok := t.contacts[jid]
+	info.Found = ok
+	return info, nil
+}
+
+func (t *FakeTransport) Logout() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.device.ID == nil {
+		return whatsmeow.ErrNotLoggedIn
+	}
+	t.connected = false
+	return t.forgetDevice()
+}
+
+// forgetDevice remove o dispositivo do store, como o whatsmeow faz no logout.
+// Deve ser chamado com t.mu travado.
+func (t *FakeTransport) forgetDevice() error {
+	var err error
+	if t.device.Container != nil {
+		err = t.device.Delete()
+	}
+	t.device.ID = nil
+	return err
+}
+
+// SetContact adiciona ou substitui um contato retornado por GetAllContacts
+func (t *FakeTransport) SetContact(jid types.JID, info types.ContactInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.contacts[jid] = info
+}
+
+// Sent retorna uma cópia das mensagens enviadas até o momento
+func (t *FakeTransport) Sent() []FakeSentMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]FakeSentMessage(nil), t.sent...)
+}
+
+// Emit entrega um evento a todos os handlers registrados, na ordem de registro
+func (t *FakeTransport) Emit(evt interface{}) {
+	t.mu.Lock()
+	handlers := append([]fakeHandler(nil), t.handlers...)
+	t.mu.Unlock()
+
+	for _, h := range handlers {
+		h.fn(evt)
+	}
+}
+
+// EmitQR emite um evento QR com os códigos informados
+func (t *FakeTransport) EmitQR(codes ...string) {
+	t.Emit(&events.QR{Codes: codes})
+}
+
+// EmitPairSuccess simula a leitura do QR: grava o JID no dispositivo e emite
+// PairSuccess seguido de Connected
+func (t *FakeTransport) EmitPairSuccess(jid types.JID) {
+	t.mu.Lock()
+	t.device.ID = &jid
+	t.connected = true
+	if t.device.Account == nil {
+		// Identidade fictícia com os tamanhos exigidos pelo schema do sqlstore
+		t.device.Account = &waAdv.ADVSignedDeviceIdentity{
+			Details:             []byte{},
+			AccountSignature:    make([]byte, 64),
+			AccountSignatureKey: make([]byte, 32),
+			DeviceSignature:     make([]byte, 64),
+		}
+	}
+	if t.device.Container != nil {
+		if err := t.device.Save(); err != nil {
+			log.Printf("[FakeTransport] Erro ao salvar dispositivo %s: %v", jid, err)
+		}
+	}
+	t.mu.Unlock()
+
+	t.Emit(&events.PairSuccess{ID: jid, Platform: "fake"})
+	t.Emit(&events.Connected{})
+}
+
+// EmitMessage simula uma mensagem de texto recebida de from
+func (t *FakeTransport) EmitMessage(from types.JID, text string) *events.Message {
+	evt := &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{Chat: from, Sender: from},
+			ID:            fakeMessageID(),
+			Type:          "text",
+			Timestamp:     time.Now(),
+		},
+		Message: &waProto.Message{Conversation: proto.String(text)},
+	}
+	t.Emit(evt)
+	return evt
+}
+
+// EmitReceipt simula um recibo (entrega, leitura, reprodução) das mensagens ids
+func (t *FakeTransport) EmitReceipt(chat types.JID, receiptType types.ReceiptType, ids ...types.MessageID) {
+	t.Emit(&events.Receipt{
+		MessageSource: types.MessageSource{Chat: chat, Sender: chat},
+		MessageIDs:    ids,
+		Timestamp:     time.Now(),
+		Type:          receiptType,
+	})
+}
+
+// EmitLoggedOut simula o logout remoto do dispositivo
+func (t *FakeTransport) EmitLoggedOut(reason events.ConnectFailureReason) {
+	t.mu.Lock()
+	t.connected = false
+	if err := t.forgetDevice(); err != nil {
+		log.Printf("[FakeTransport] Erro ao remover dispositivo: %v", err)
+	}
+	t.mu.Unlock()
+
+	t.Emit(&events.LoggedOut{Reason: reason})
+}
+
+// EmitKeepAliveTimeout simula keepalives sem resposta desde lastSuccess,
+// com o socket ainda aparentemente aberto
+func (t *FakeTransport) EmitKeepAliveTimeout(errorCount int, lastSuccess time.Time) {
+	t.Emit(&events.KeepAliveTimeout{ErrorCount: errorCount, LastSuccess: lastSuccess})
+}
+
+// DropConnection simula um socket que morre sem emitir Disconnected
+func (t *FakeTransport) DropConnection() {
+	t.mu.Lock()
+	t.connected = false
+	t.mu.Unlock()
+}
+
+// fakeQRChannel converte os eventos do FakeTransport em itens de canal de QR
+type fakeQRChannel struct {
+	transport *FakeTransport
+	ctx       context.Context
+	output    chan whatsmeow.QRChannelItem
+	stop      chan struct{}
+	handlerID uint32
+	mu        sync.Mutex
+	closed    bool
+}
+
+// send entrega um item sem bloquear; retorna false se o canal estiver fechado ou cheio
+func (qrc *fakeQRChannel) send(item whatsmeow.QRChannelItem) bool {
+	qrc.mu.Lock()
+	defer qrc.mu.Unlock()
+	if qrc.closed {
+		return false
+	}
+	select {
+	case qrc.output <- item:
+		return true
+	default:
+		return false
+	}
+}
+
+// finish emite o item final (se houver) e fecha o canal
+func (qrc *fakeQRChannel) finish(item *whatsmeow.QRChannelItem, disconnect bool) {
+	qrc.mu.Lock()
+	if qrc.closed {
+		qrc.mu.Unlock()
+		return
+	}
+	qrc.closed = true
+	close(qrc.stop)
+	if item != nil {
+		select {
+		case qrc.output <- *item:
+		default:
+		}
+	}
+	close(qrc.output)
+	qrc.mu.Unlock()
+
+	go qrc.transport.removeEventHandler(qrc.handlerID)
+	if disconnect {
+		qrc.transport.Disconnect()
+	}
+}
+
+func (qrc *fakeQRChannel) handleEvent(rawEvt interface{}) {
+	switch evt := rawEvt.(type) {
+	case *events.QR:
+		go qrc.emit(evt.Codes)
+	case *events.PairSuccess:
+		qrc.finish(&whatsmeow.QRChannelSuccess, false)
+	case *events.Disconnected:
+		qrc.finish(&whatsmeow.QRChannelTimeout, false)
+	case *events.Connected, *events.LoggedOut:
+		qrc.finish(&whatsmeow.QRChannelErrUnexpectedEvent, false)
+	}
+}
+
+func (qrc *fakeQRChannel) emit(codes []string) {
+	for _, code := range codes {
+		item := whatsmeow.QRChannelItem{Event: whatsmeow.QRChannelEventCode, Code: code, Timeout: qrc.transport.QRInterval}
+		if !qrc.send(item) {
+			qrc.finish(nil, true)
+			return
+		}
+
+		select {
+		case <-time.After(qrc.transport.QRInterval):
+		case <-qrc.stop:
+			return
+		case <-qrc.ctx.Done():
+			qrc.finish(nil, true)
+			return
+		}
+	}
+	qrc.finish(&whatsmeow.QRChannelTimeout, true)
+}
+
+func fakeQRCodes(n int) []string {
+	codes := make([]string, n)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("2@fake-%s,%d", uuid.New().String(), i)
+	}
+	return codes
+}
+
+func fakeJID() types.JID {
+	return types.NewADJID(fmt.Sprintf("5500%09d", rand.Intn(1000000000)), 0, 1)
+}
+
+func fakeMessageID() types.MessageID {
+	return "FAKE" + strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:16])
+}
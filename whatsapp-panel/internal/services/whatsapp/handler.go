@@ -1,71 +1,110 @@
 package whatsapp
 
 import (
-	"fmt"
+	"log"
 	"sync/atomic"
 
+	"whatsapp-panel/internal/models"
 	"whatsapp-panel/internal/storage"
 
 	"go.mau.fi/whatsmeow/types/events"
 )
 
+// EventHandler persiste no banco do painel os eventos de uma sessão: dados de
+// pareamento, horários de conexão e estatísticas. As mudanças de status são
+// gravadas pela máquina de estados do Client.
 type EventHandler struct {
 	DB        *storage.Database
 	SessionID string
+	// TenantID é o workspace gravado na linha da sessão ao parear
+	TenantID string
+	// Transport, se definido, é usado para contar os contatos ao conectar
+	Transport Transport
 	Stats     struct {
-		MessageCount int64
-		Contacts     int
-		Groups       int
+		MessageCount  int64
+		Contacts      int
+		Groups        int
+		Conversations int
 	}
 }
 
 func NewEventHandler(db *storage.Database, sessionID string) *EventHandler {
-	return &EventHandler{
+	h := &EventHandler{
 		DB:        db,
 		SessionID: sessionID,
 	}
+
+	// Continuar a contagem a partir das estatísticas já gravadas
+	if stats, err := db.GetSessionStats(sessionID); err != nil {
+		log.Printf("[Events %s] Erro ao carregar estatísticas: %v", sessionID, err)
+	} else {
+		h.Stats.MessageCount = stats.MessageCount
+		h.Stats.Contacts = stats.Contacts
+		h.Stats.Groups = stats.Groups
+		h.Stats.Conversations = stats.Conversations
+	}
+
+	return h
 }
 
 func (h *EventHandler) Handle(evt interface{}) {
 	switch v := evt.(type) {
-	case *events.Connected:
-		fmt.Printf("[%s] ✅ Conectado ao WhatsApp\n", h.SessionID)
-		// Quando conectado, apenas atualiza o status
-		h.DB.SaveSession(h.SessionID, "WhatsApp", "", "")
-
 	case *events.PairSuccess:
-		fmt.Printf("[%s] 🔗 Pareamento bem sucedido!\n", h.SessionID)
-		phoneNumber := v.ID.User
-		h.DB.SaveSession(h.SessionID, "WhatsApp", v.ID.String(), phoneNumber)
-
-	case *events.ClientOutdated:
-		fmt.Println("Cliente desatualizado")
+		log.Printf("[Events %s] 🔗 Pareamento bem sucedido, gravando sessão", h.SessionID)
+		jid := v.ID.ToNonAD()
+		if err := h.DB.SaveSession(h.SessionID, h.TenantID, "WhatsApp", jid.String(), jid.User, models.StatusPaired); err != nil {
+			log.Printf("[Events %s] Erro ao gravar sessão: %v", h.SessionID, err)
+		}
 
-	case *events.Disconnected:
-		fmt.Printf("Desconectado do WhatsApp\n")
+	case *events.Connected:
+		if err := h.DB.MarkSessionConnected(h.SessionID); err != nil {
+			log.Printf("[Events %s] Erro ao atualizar conexão: %v", h.SessionID, err)
+		}
+		h.refreshContacts()
+		h.updateStats()
 
 	case *events.Message:
+		h.storeMessage(v)
 		// Incrementa o contador de mensagens
 		atomic.AddInt64(&h.Stats.MessageCount, 1)
-		fmt.Printf("Mensagem recebida de %s\n", v.Info.Sender.String())
 		// Atualiza estatísticas após cada mensagem
 		h.updateStats()
 
+	case *events.Receipt:
+		h.handleReceipt(v)
+
 	case *events.LoggedOut:
-		fmt.Println("Deslogado do WhatsApp")
-		// Remove a sessão quando deslogado
-		h.DB.DeleteSession(h.SessionID)
+		// O status logged_out é gravado pela máquina de estados; aqui apenas
+		// garantimos que as estatísticas finais fiquem registradas
+		log.Printf("[Events %s] Sessão deslogada, linha mantida como logged_out", h.SessionID)
+		h.updateStats()
+	}
+}
 
-	default:
-		fmt.Printf("Evento não tratado: %T\n", v)
+// refreshContacts atualiza a contagem de contatos a partir do store do dispositivo
+func (h *EventHandler) refreshContacts() {
+	if h.Transport == nil {
+		return
+	}
+	contacts, err := h.Transport.GetAllContacts()
+	if err != nil {
+		log.Printf("[Events %s] Erro ao contar contatos: %v", h.SessionID, err)
+		return
 	}
+	h.Stats.Contacts = len(contacts)
 }
 
-func (h *EventHandler) updateStats() {
-	h.DB.UpdateSessionStats(
+// updateStats grava as estatísticas da sessão, registrando no log as falhas
+func (h *EventHandler) updateStats() error {
+	err := h.DB.UpdateSessionStats(
 		h.SessionID,
 		h.Stats.Contacts,
 		h.Stats.Groups,
-		int(atomic.LoadInt64(&h.Stats.MessageCount)),
+		h.Stats.Conversations,
+		atomic.LoadInt64(&h.Stats.MessageCount),
 	)
+	if err != nil {
+		log.Printf("[Events %s] Erro ao atualizar estatísticas: %v", h.SessionID, err)
+	}
+	return err
 }
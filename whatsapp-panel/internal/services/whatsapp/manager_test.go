@@ -0,0 +1,73 @@
+package whatsapp
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+
+	"whatsapp-panel/internal/config"
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/storage"
+)
+
+// newTestManager cria um Manager com transporte simulado, banco do painel e
+// diretório de sessões temporários
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := storage.NewDatabase(filepath.Join(dir, "panel.db"))
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	m, err := NewManager(&config.Config{
+		SessionsDir:         filepath.Join(dir, "sessions"),
+		ReconnectMultiplier: 2,
+	}, db)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	m.NewTransport = FakeTransportFactory(0)
+	t.Cleanup(func() {
+		m.Shutdown(context.Background())
+		db.Close()
+	})
+	return m
+}
+
+// pairTestClient cria uma sessão e simula a leitura do QR, retornando o
+// cliente já conectado e o seu transporte
+func pairTestClient(t *testing.T, m *Manager) (*Client, *FakeTransport) {
+	t.Helper()
+	client, err := m.NewClient(models.DefaultTenantID)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	transport := client.WAClient.(*FakeTransport)
+	qr, err := transport.GetQRChannel(context.Background())
+	if err != nil {
+		t.Fatalf("GetQRChannel: %v", err)
+	}
+	if err := transport.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	<-qr
+	transport.EmitPairSuccess(types.NewADJID("5511999990000", 0, 1))
+	for range qr {
+	}
+	return client, transport
+}
+
+// waitFor aguarda cond ser verdadeira, falhando o teste após o prazo
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("tempo esgotado aguardando %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
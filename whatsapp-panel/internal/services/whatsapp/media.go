@@ -0,0 +1,369 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"google.golang.org/protobuf/proto"
+
+	"whatsapp-panel/internal/models"
+)
+
+// Tipos de mídia enviados pelo painel
+const (
+	MediaKindImage    = "image"
+	MediaKindVideo    = "video"
+	MediaKindAudio    = "audio"
+	MediaKindDocument = "document"
+)
+
+// Limites de tamanho do WhatsApp por tipo de mídia
+var mediaKindLimits = map[string]int64{
+	MediaKindImage:    16 << 20,
+	MediaKindVideo:    64 << 20,
+	MediaKindAudio:    16 << 20,
+	MediaKindDocument: 100 << 20,
+}
+
+var (
+	// ErrInvalidMedia indica uma mídia que não pode ser enviada como pedido
+	ErrInvalidMedia = errors.New("mídia inválida")
+	// ErrMediaTooLarge indica uma mídia acima do limite
+	ErrMediaTooLarge = errors.New("mídia muito grande")
+)
+
+// MediaPolicy configura o recebimento de mídias pela API
+type MediaPolicy struct {
+	MaxSize          int64         // tamanho máximo de um arquivo enviado ou baixado
+	FetchTimeout     time.Duration // prazo para baixar uma mídia informada por URL
+	AllowPrivateURLs bool          // permite baixar de endereços de rede interna
+}
+
+// Media é uma mídia a enviar. Kind e MimeType são preenchidos por DetectMedia.
+type Media struct {
+	Data       []byte
+	FileName   string
+	MimeType   string // tipo informado pelo remetente, usado se o conteúdo não for reconhecido
+	Caption    string
+	PTT        bool // envia um áudio OGG/Opus como nota de voz
+	AsDocument bool // envia imagens, vídeos e áudios como documento, sem compressão
+
+	Kind string
+}
+
+// Tipos que o http.DetectContentType não distingue pelo conteúdo
+var mediaExtensions = map[string]string{
+	".aac":  "audio/aac",
+	".amr":  "audio/amr",
+	".m4a":  "audio/mp4",
+	".mp3":  "audio/mpeg",
+	".oga":  "audio/ogg",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".3gp":  "video/3gpp",
+	".csv":  "text/csv",
+	".doc":  "application/msword",
+	".xls":  "application/vnd.ms-excel",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// DetectMedia identifica o tipo MIME pelo conteúdo (com a extensão do nome e
+// o tipo informado para formatos ambíguos), escolhe o tipo de mensagem e
+// aplica os limites de tamanho
+func DetectMedia(media *Media) error {
+	if len(media.Data) == 0 {
+		return fmt.Errorf("%w: arquivo vazio", ErrInvalidMedia)
+	}
+
+	mimeType := baseMimeType(http.DetectContentType(media.Data))
+	switch mimeType {
+	case "application/octet-stream", "text/plain", "application/zip", "application/ogg", "video/mp4":
+		// Formatos de contêiner: a extensão ou o tipo informado são mais precisos
+		if byExt := mimeTypeByExtension(media.FileName); byExt != "" {
+			mimeType = byExt
+		} else if declared := baseMimeType(media.MimeType); declared != "" && declared != "application/octet-stream" {
+			mimeType = declared
+		} else if mimeType == "application/ogg" {
+			mimeType = "audio/ogg"
+		}
+	}
+	media.MimeType = mimeType
+
+	media.Kind = MediaKindDocument
+	switch mimeType {
+	case "image/jpeg", "image/png":
+		media.Kind = MediaKindImage
+	case "video/mp4", "video/3gpp":
+		media.Kind = MediaKindVideo
+	case "audio/ogg", "audio/mpeg", "audio/mp4", "audio/aac", "audio/amr":
+		media.Kind = MediaKindAudio
+	}
+
+	if media.PTT {
+		if media.Kind != MediaKindAudio || mimeType != "audio/ogg" || media.AsDocument {
+			return fmt.Errorf("%w: notas de voz devem ser áudio OGG/Opus (recebido %s)", ErrInvalidMedia, mimeType)
+		}
+		media.MimeType = "audio/ogg; codecs=opus"
+	}
+	if media.AsDocument {
+		media.Kind = MediaKindDocument
+	}
+	if media.Kind == MediaKindAudio && media.Caption != "" {
+		return fmt.Errorf("%w: áudios não aceitam legenda", ErrInvalidMedia)
+	}
+
+	if limit := mediaKindLimits[media.Kind]; int64(len(media.Data)) > limit {
+		return fmt.Errorf("%w: %s acima de %d MB", ErrMediaTooLarge, media.Kind, limit>>20)
+	}
+	return nil
+}
+
+// baseMimeType remove parâmetros (ex: "; charset=utf-8") de um tipo MIME
+func baseMimeType(mimeType string) string {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// mimeTypeByExtension retorna o tipo MIME pela extensão do nome do arquivo
+func mimeTypeByExtension(fileName string) string {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if ext == "" {
+		return ""
+	}
+	if mimeType, ok := mediaExtensions[ext]; ok {
+		return mimeType
+	}
+	return baseMimeType(mime.TypeByExtension(ext))
+}
+
+// SendMediaMessage envia uma imagem, vídeo, áudio ou documento para um número.
+// A mídia é cifrada e enviada aos servidores do WhatsApp antes da mensagem,
+// que é registrada para o rastreamento de entrega.
+func (c *Client) SendMediaMessage(phoneNumber string, media *Media) (*models.Message, error) {
+	if !c.Connected {
+		return nil, ErrNotConnected
+	}
+	if err := DetectMedia(media); err != nil {
+		return nil, err
+	}
+
+	if err := c.beginSend(); err != nil {
+		return nil, err
+	}
+	defer c.endSend()
+
+	recipient, err := recipientJID(phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	slot, err := c.acquireSendSlot(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	uploaded, err := c.WAClient.Upload(ctx, media.Data, whatsmeowMediaType(media.Kind))
+	if err != nil {
+		c.limiter.release(slot)
+		return nil, fmt.Errorf("erro ao enviar mídia: %v", err)
+	}
+
+	resp, err := c.WAClient.SendMessage(ctx, recipient, buildMediaMessage(media, uploaded))
+	if err != nil {
+		c.limiter.release(slot)
+		return nil, fmt.Errorf("erro ao enviar mensagem: %v", err)
+	}
+	ref := &models.MessageMedia{
+		MimeType:   media.MimeType,
+		Size:       int64(uploaded.FileLength),
+		DirectPath: uploaded.DirectPath,
+	}
+	if media.Kind == MediaKindDocument {
+		ref.FileName = documentFileName(media)
+	}
+	return c.recordSent(&models.Message{
+		PhoneNumber: phoneNumber,
+		Type:        media.Kind,
+		Body:        media.Caption,
+		Media:       ref,
+	}, resp), nil
+}
+
+// whatsmeowMediaType retorna o tipo usado pelo whatsmeow para cifrar a mídia
+func whatsmeowMediaType(kind string) whatsmeow.MediaType {
+	switch kind {
+	case MediaKindImage:
+		return whatsmeow.MediaImage
+	case MediaKindVideo:
+		return whatsmeow.MediaVideo
+	case MediaKindAudio:
+		return whatsmeow.MediaAudio
+	default:
+		return whatsmeow.MediaDocument
+	}
+}
+
+// buildMediaMessage monta a mensagem do tipo da mídia com os dados do upload
+func buildMediaMessage(media *Media, uploaded whatsmeow.UploadResponse) *waProto.Message {
+	caption := proto.String(media.Caption)
+	if media.Caption == "" {
+		caption = nil
+	}
+
+	switch media.Kind {
+	case MediaKindImage:
+		return &waProto.Message{ImageMessage: &waProto.ImageMessage{
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+			Mimetype:      proto.String(media.MimeType),
+			Caption:       caption,
+		}}
+	case MediaKindVideo:
+		return &waProto.Message{VideoMessage: &waProto.VideoMessage{
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+			Mimetype:      proto.String(media.MimeType),
+			Caption:       caption,
+		}}
+	case MediaKindAudio:
+		return &waProto.Message{AudioMessage: &waProto.AudioMessage{
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+			Mimetype:      proto.String(media.MimeType),
+			PTT:           proto.Bool(media.PTT),
+		}}
+	default:
+		fileName := documentFileName(media)
+		return &waProto.Message{DocumentMessage: &waProto.DocumentMessage{
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+			Mimetype:      proto.String(media.MimeType),
+			FileName:      proto.String(fileName),
+			Title:         proto.String(fileName),
+			Caption:       caption,
+		}}
+	}
+}
+
+// documentFileName retorna o nome exibido de um documento, com uma extensão
+// compatível com o tipo quando o remetente não informou o nome
+func documentFileName(media *Media) string {
+	if name := filepath.Base(media.FileName); media.FileName != "" && name != "." && name != "/" {
+		return name
+	}
+	name := "arquivo"
+	if exts, _ := mime.ExtensionsByType(media.MimeType); len(exts) > 0 {
+		name += exts[0]
+	}
+	return name
+}
+
+// FetchMedia baixa a mídia de uma URL http(s), respeitando o tamanho máximo e o
+// prazo da política. Endereços de rede interna são recusados, salvo se
+// AllowPrivateURLs estiver ativo.
+func (m *Manager) FetchMedia(ctx context.Context, rawURL string) (*Media, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: URL deve ser http:// ou https://", ErrInvalidMedia)
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if !m.Media.AllowPrivateURLs {
+		// A verificação é feita no endereço efetivamente conectado, valendo
+		// também para redirecionamentos e nomes que resolvem para a rede interna
+		dialer.Control = rejectPrivateAddress
+		transport.Proxy = nil
+	}
+	transport.DialContext = dialer.DialContext
+	client := &http.Client{Transport: transport, Timeout: m.Media.FetchTimeout}
+	defer transport.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMedia, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: erro ao baixar mídia: %v", ErrInvalidMedia, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: URL retornou status %d", ErrInvalidMedia, resp.StatusCode)
+	}
+	if resp.ContentLength > m.Media.MaxSize {
+		return nil, fmt.Errorf("%w: arquivo acima de %d MB", ErrMediaTooLarge, m.Media.MaxSize>>20)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, m.Media.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: erro ao baixar mídia: %v", ErrInvalidMedia, err)
+	}
+	if int64(len(data)) > m.Media.MaxSize {
+		return nil, fmt.Errorf("%w: arquivo acima de %d MB", ErrMediaTooLarge, m.Media.MaxSize>>20)
+	}
+
+	media := &Media{Data: data, MimeType: resp.Header.Get("Content-Type")}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		media.FileName = params["filename"]
+	}
+	if media.FileName == "" {
+		if name := path.Base(resp.Request.URL.Path); name != "/" && name != "." {
+			media.FileName = name
+		}
+	}
+	return media, nil
+}
+
+// rejectPrivateAddress recusa conexões a loopback, redes privadas, link-local
+// e demais endereços que não são públicos
+func rejectPrivateAddress(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("endereço inválido: %s", host)
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() || cgnatRange.Contains(ip) {
+		return fmt.Errorf("endereço de rede interna não permitido: %s", host)
+	}
+	return nil
+}
+
+// cgnatRange é o espaço compartilhado 100.64.0.0/10, também interno
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
@@ -0,0 +1,249 @@
+package whatsapp
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/storage"
+)
+
+// Histórico e rastreamento de entrega: toda mensagem aceita pelo WhatsApp é
+// gravada em messages com o ID retornado pelo whatsmeow, assim como as
+// recebidas pela sessão. Os eventos Receipt avançam o estado das enviadas de
+// sent para delivered, read e played.
+
+// Tipos de mensagem além das mídias (MediaKind*)
+const (
+	messageTypeText     = "text"
+	messageTypeSticker  = "sticker"
+	messageTypeLocation = "location"
+	messageTypeContact  = "contact"
+)
+
+// recordSent grava a mensagem aceita pelo WhatsApp com o ID e o horário de
+// resp. Sem msg.ID um novo é gerado; a fila de envio usa o ID da própria
+// mensagem, para que o message_id devolvido pela API sirva em GET /messages/:id.
+func (c *Client) recordSent(msg *models.Message, resp whatsmeow.SendResponse) *models.Message {
+	if msg.ID == "" {
+		msg.ID = uuid.New().String()
+	}
+	sentAt := resp.Timestamp
+	if sentAt.IsZero() {
+		sentAt = time.Now()
+	}
+	msg.SessionID = c.ID
+	msg.TenantID = c.TenantID
+	msg.WAMessageID = resp.ID
+	msg.Direction = models.MessageOutbound
+	msg.Status = models.MessageSent
+	msg.Timestamp, msg.SentAt = sentAt, &sentAt
+	if jid, err := recipientJID(msg.PhoneNumber); err == nil {
+		msg.ChatJID = jid.String()
+	}
+	if c.WAClient != nil {
+		if own := c.WAClient.DeviceID(); own != nil {
+			msg.SenderJID = own.ToNonAD().String()
+		}
+	}
+	if c.DB == nil {
+		return msg
+	}
+	if err := c.DB.RecordMessage(msg); err != nil {
+		log.Printf("[Client %s] Erro ao registrar mensagem %s: %v", c.ID, resp.ID, err)
+	}
+	return msg
+}
+
+// GetMessage retorna o estado de entrega de uma mensagem do tenant, pelo ID
+// do painel ou do WhatsApp. Mensagens que ainda estão na fila de envio (ou
+// que foram para dead-letter) aparecem como queued ou failed.
+func (m *Manager) GetMessage(tenantID, id string) (*models.Message, error) {
+	msg, err := m.DB.GetMessage(tenantID, id)
+	if err != storage.ErrMessageNotFound {
+		return msg, err
+	}
+
+	out, err := m.DB.GetOutbound(tenantID, id)
+	if err == storage.ErrOutboundNotFound {
+		return nil, storage.ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	msg = &models.Message{
+		ID:          out.ID,
+		SessionID:   out.SessionID,
+		TenantID:    out.TenantID,
+		WAMessageID: out.WAMessageID,
+		Direction:   models.MessageOutbound,
+		PhoneNumber: out.PhoneNumber,
+		Type:        messageTypeText,
+		Body:        out.Body,
+		Status:      models.MessageQueued,
+		Error:       out.LastError,
+		Timestamp:   out.CreatedAt,
+		SentAt:      out.SentAt,
+		CreatedAt:   out.CreatedAt,
+		UpdatedAt:   out.UpdatedAt,
+	}
+	switch out.Status {
+	case models.OutboundDead:
+		msg.Status = models.MessageFailed
+	case models.OutboundSent:
+		// Enviada antes do rastreamento de entrega existir
+		msg.Status = models.MessageSent
+	}
+	return msg, nil
+}
+
+// receiptStatus retorna o estado de entrega correspondente a um recibo, ou
+// vazio para recibos que não dizem respeito ao destinatário (ex: leitura em
+// outro aparelho da própria conta)
+func receiptStatus(t types.ReceiptType) string {
+	switch t {
+	case types.ReceiptTypeDelivered:
+		return models.MessageDelivered
+	case types.ReceiptTypeRead:
+		return models.MessageRead
+	case types.ReceiptTypePlayed:
+		return models.MessagePlayed
+	default:
+		return ""
+	}
+}
+
+// handleReceipt aplica um recibo às mensagens enviadas pela sessão
+func (h *EventHandler) handleReceipt(evt *events.Receipt) {
+	status := receiptStatus(evt.Type)
+	if status == "" || evt.IsFromMe {
+		return
+	}
+	ids := make([]string, len(evt.MessageIDs))
+	for i, id := range evt.MessageIDs {
+		ids[i] = string(id)
+	}
+	at := evt.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	if _, err := h.DB.MarkMessagesReceipt(h.SessionID, ids, status, at); err != nil {
+		log.Printf("[Events %s] Erro ao registrar recibo %s: %v", h.SessionID, status, err)
+	}
+}
+
+// storeMessage grava no histórico uma mensagem recebida pela sessão ou
+// enviada pelo próprio aparelho
+func (h *EventHandler) storeMessage(evt *events.Message) {
+	msg := &models.Message{
+		ID:          uuid.New().String(),
+		SessionID:   h.SessionID,
+		TenantID:    h.TenantID,
+		WAMessageID: string(evt.Info.ID),
+		Direction:   models.MessageInbound,
+		ChatJID:     evt.Info.Chat.ToNonAD().String(),
+		SenderJID:   evt.Info.Sender.ToNonAD().String(),
+		SenderName:  evt.Info.PushName,
+		Status:      models.MessageReceived,
+		Timestamp:   evt.Info.Timestamp,
+	}
+	if !fillMessageContent(msg, evt.Message) {
+		return // reações, edições, mensagens de protocolo etc.
+	}
+	if evt.Info.IsFromMe {
+		msg.Direction, msg.Status = models.MessageOutbound, models.MessageSent
+		msg.SentAt = &msg.Timestamp
+	}
+	if evt.Info.Chat.Server == types.DefaultUserServer {
+		msg.PhoneNumber = evt.Info.Chat.User
+	}
+	if msg.TenantID == "" {
+		tenantID, err := h.DB.GetSessionTenant(h.SessionID)
+		if err != nil {
+			log.Printf("[Events %s] Erro ao buscar workspace da sessão: %v", h.SessionID, err)
+			return
+		}
+		h.TenantID, msg.TenantID = tenantID, tenantID
+	}
+	if err := h.DB.RecordMessage(msg); err != nil {
+		log.Printf("[Events %s] Erro ao gravar mensagem %s: %v", h.SessionID, evt.Info.ID, err)
+	}
+}
+
+// contextInfoMessage é uma mensagem que pode citar outra
+type contextInfoMessage interface {
+	GetContextInfo() *waProto.ContextInfo
+}
+
+// mediaMessage são os campos comuns às mensagens de mídia
+type mediaMessage interface {
+	contextInfoMessage
+	GetMimetype() string
+	GetFileLength() uint64
+	GetDirectPath() string
+}
+
+// fillMessageContent preenche o tipo, o texto, a mídia e a mensagem citada a
+// partir do conteúdo recebido. Retorna false para conteúdos que não entram no
+// histórico.
+func fillMessageContent(msg *models.Message, content *waProto.Message) bool {
+	var quoted contextInfoMessage
+	var media mediaMessage
+	switch {
+	case content == nil:
+		return false
+	case content.GetConversation() != "":
+		msg.Type, msg.Body = messageTypeText, content.GetConversation()
+	case content.GetExtendedTextMessage() != nil:
+		m := content.GetExtendedTextMessage()
+		msg.Type, msg.Body, quoted = messageTypeText, m.GetText(), m
+	case content.GetImageMessage() != nil:
+		m := content.GetImageMessage()
+		msg.Type, msg.Body, media = MediaKindImage, m.GetCaption(), m
+	case content.GetVideoMessage() != nil:
+		m := content.GetVideoMessage()
+		msg.Type, msg.Body, media = MediaKindVideo, m.GetCaption(), m
+	case content.GetAudioMessage() != nil:
+		msg.Type, media = MediaKindAudio, content.GetAudioMessage()
+	case content.GetDocumentMessage() != nil:
+		m := content.GetDocumentMessage()
+		msg.Type, msg.Body, media = MediaKindDocument, m.GetCaption(), m
+		msg.Media = &models.MessageMedia{FileName: m.GetFileName()}
+	case content.GetStickerMessage() != nil:
+		msg.Type, media = messageTypeSticker, content.GetStickerMessage()
+	case content.GetLocationMessage() != nil:
+		m := content.GetLocationMessage()
+		msg.Type, quoted = messageTypeLocation, m
+		msg.Body = fmt.Sprintf("%f,%f", m.GetDegreesLatitude(), m.GetDegreesLongitude())
+		if name := strings.TrimSpace(m.GetName() + " " + m.GetAddress()); name != "" {
+			msg.Body = name + " (" + msg.Body + ")"
+		}
+	case content.GetContactMessage() != nil:
+		m := content.GetContactMessage()
+		msg.Type, msg.Body, quoted = messageTypeContact, m.GetDisplayName(), m
+	default:
+		return false
+	}
+
+	if media != nil {
+		quoted = media
+		if msg.Media == nil {
+			msg.Media = &models.MessageMedia{}
+		}
+		msg.Media.MimeType = media.GetMimetype()
+		msg.Media.Size = int64(media.GetFileLength())
+		msg.Media.DirectPath = media.GetDirectPath()
+	}
+	if quoted != nil {
+		msg.QuotedMessageID = quoted.GetContextInfo().GetStanzaID()
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+
+	"whatsapp-panel/internal/models"
+)
+
+func TestReceiptProgression(t *testing.T) {
+	m := newTestManager(t)
+	client, transport := pairTestClient(t, m)
+	if err := client.SendTextMessage("5511987654321", "oi"); err != nil {
+		t.Fatalf("SendTextMessage: %v", err)
+	}
+	sent := transport.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("mensagens enviadas = %d, quer 1", len(sent))
+	}
+	waID, chat := sent[0].ID, sent[0].To
+
+	message := func() *models.Message {
+		t.Helper()
+		msg, err := m.GetMessage(models.DefaultTenantID, string(waID))
+		if err != nil {
+			t.Fatalf("GetMessage: %v", err)
+		}
+		return msg
+	}
+	if msg := message(); msg.Status != models.MessageSent || msg.SentAt == nil || msg.DeliveredAt != nil {
+		t.Fatalf("mensagem enviada = %s, sent_at %v, delivered_at %v", msg.Status, msg.SentAt, msg.DeliveredAt)
+	}
+
+	// Leitura em outro aparelho da própria conta não altera o estado
+	transport.EmitReceipt(chat, types.ReceiptTypeReadSelf, waID)
+	if msg := message(); msg.Status != models.MessageSent {
+		t.Errorf("status após read-self = %s, quer %s", msg.Status, models.MessageSent)
+	}
+
+	// Leitura sem recibo de entrega: a entrega recebe o mesmo horário
+	transport.EmitReceipt(chat, types.ReceiptTypeRead, waID)
+	msg := message()
+	if msg.Status != models.MessageRead || msg.ReadAt == nil || msg.DeliveredAt == nil || !msg.DeliveredAt.Equal(*msg.ReadAt) {
+		t.Fatalf("após leitura = %s, delivered_at %v, read_at %v", msg.Status, msg.DeliveredAt, msg.ReadAt)
+	}
+	readAt := *msg.ReadAt
+
+	// Recibo de entrega atrasado não faz o estado retroceder
+	time.Sleep(10 * time.Millisecond)
+	transport.EmitReceipt(chat, types.ReceiptTypeDelivered, waID)
+	msg = message()
+	if msg.Status != models.MessageRead || !msg.DeliveredAt.Equal(readAt) {
+		t.Errorf("após entrega atrasada = %s, delivered_at %v, quer %s e %v", msg.Status, msg.DeliveredAt, models.MessageRead, readAt)
+	}
+
+	transport.EmitReceipt(chat, types.ReceiptTypePlayed, waID)
+	msg = message()
+	if msg.Status != models.MessagePlayed || msg.PlayedAt == nil || !msg.ReadAt.Equal(readAt) {
+		t.Errorf("após reprodução = %s, played_at %v, read_at %v", msg.Status, msg.PlayedAt, msg.ReadAt)
+	}
+}
@@ -0,0 +1,288 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/storage"
+)
+
+// Fila de envio durável: as mensagens são gravadas em outbound_messages e
+// entregues por um worker por sessão, com novas tentativas e backoff. Após
+// MaxAttempts falhas a mensagem vai para dead-letter, de onde pode ser
+// devolvida à fila pela API.
+
+// outboxPollInterval é a espera do worker enquanto a sessão está desconectada
+const outboxPollInterval = 5 * time.Second
+
+// ErrOutboundNotDead indica uma tentativa de reenviar mensagem fora de dead-letter
+var ErrOutboundNotDead = errors.New("apenas mensagens em dead-letter podem ser reenviadas")
+
+// OutboxPolicy configura as novas tentativas da fila de envio
+type OutboxPolicy struct {
+	MaxAttempts   int           // tentativas antes de dead-letter (0 = sem limite)
+	RetryDelay    time.Duration // atraso antes da segunda tentativa, dobrado a cada falha
+	RetryMaxDelay time.Duration // limite superior do atraso entre tentativas
+}
+
+// Delay retorna o atraso antes da próxima tentativa, após attempts falhas
+func (p OutboxPolicy) Delay(attempts int) time.Duration {
+	backoff := ReconnectPolicy{InitialDelay: p.RetryDelay, MaxDelay: p.RetryMaxDelay, Multiplier: 2, Jitter: 0.2}
+	return backoff.Delay(attempts)
+}
+
+// outboxWorkers guarda os workers da fila, um por sessão com mensagens pendentes
+type outboxWorkers struct {
+	mu      sync.Mutex
+	workers map[string]chan struct{} // sinal de nova mensagem, por sessão
+	stop    chan struct{}            // nil antes de StartOutbox e depois de StopOutbox
+	wg      sync.WaitGroup
+}
+
+// StartOutbox inicia a entrega da fila: mensagens que estavam em envio quando
+// o processo parou voltam à fila e cada sessão com pendências ganha um worker.
+// Deve ser chamado depois de RestoreSessions.
+func (m *Manager) StartOutbox() error {
+	m.outbox.mu.Lock()
+	defer m.outbox.mu.Unlock()
+
+	if m.outbox.stop != nil {
+		return nil
+	}
+	if n, err := m.DB.ResetSendingOutbound(); err != nil {
+		return err
+	} else if n > 0 {
+		log.Printf("[Outbox] %d mensagens interrompidas voltaram à fila (podem ser entregues em duplicidade)", n)
+	}
+
+	sessions, err := m.DB.ListOutboundSessions()
+	if err != nil {
+		return err
+	}
+	m.outbox.stop = make(chan struct{})
+	m.outbox.workers = make(map[string]chan struct{})
+	for _, sessionID := range sessions {
+		m.startOutboxWorker(sessionID)
+	}
+	log.Printf("[Outbox] Fila de envio iniciada (%d sessões com mensagens pendentes)", len(sessions))
+	return nil
+}
+
+// StopOutbox encerra os workers e aguarda a conclusão da mensagem em
+// andamento de cada um, até o prazo de ctx
+func (m *Manager) StopOutbox(ctx context.Context) {
+	m.outbox.mu.Lock()
+	if m.outbox.stop == nil {
+		m.outbox.mu.Unlock()
+		return
+	}
+	close(m.outbox.stop)
+	m.outbox.stop = nil
+	m.outbox.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.outbox.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("[Outbox] Prazo para encerrar os workers expirado")
+	}
+}
+
+// EnqueueMessage grava uma mensagem de texto na fila da sessão e retorna
+// imediatamente; a entrega é feita pelo worker da sessão
+func (m *Manager) EnqueueMessage(tenantID, sessionID, phoneNumber, text string) (*models.OutboundMessage, error) {
+	if _, exists := m.GetClient(tenantID, sessionID); !exists {
+		return nil, storage.ErrSessionNotFound
+	}
+	if _, err := recipientJID(phoneNumber); err != nil {
+		return nil, err
+	}
+
+	msg := &models.OutboundMessage{
+		ID:          uuid.New().String(),
+		SessionID:   sessionID,
+		TenantID:    tenantID,
+		PhoneNumber: phoneNumber,
+		Body:        text,
+		Status:      models.OutboundQueued,
+	}
+	if err := m.DB.EnqueueOutbound(msg); err != nil {
+		return nil, err
+	}
+	m.wakeOutbox(sessionID)
+	return msg, nil
+}
+
+// RetryOutbound devolve uma mensagem em dead-letter à fila da sessão
+func (m *Manager) RetryOutbound(tenantID, sessionID, messageID string) (*models.OutboundMessage, error) {
+	msg, err := m.DB.GetOutbound(tenantID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.SessionID != sessionID {
+		return nil, storage.ErrOutboundNotFound
+	}
+	if msg.Status != models.OutboundDead {
+		return nil, ErrOutboundNotDead
+	}
+	if err := m.DB.RequeueDeadOutbound(tenantID, messageID); err != nil {
+		return nil, err
+	}
+	m.wakeOutbox(sessionID)
+	return m.DB.GetOutbound(tenantID, messageID)
+}
+
+// wakeOutbox avisa o worker da sessão sobre uma nova mensagem, criando-o se necessário
+func (m *Manager) wakeOutbox(sessionID string) {
+	m.outbox.mu.Lock()
+	defer m.outbox.mu.Unlock()
+
+	if m.outbox.stop == nil {
+		return
+	}
+	if wake, ok := m.outbox.workers[sessionID]; ok {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+		return
+	}
+	m.startOutboxWorker(sessionID)
+}
+
+// startOutboxWorker deve ser chamado com m.outbox.mu travado
+func (m *Manager) startOutboxWorker(sessionID string) {
+	wake := make(chan struct{}, 1)
+	m.outbox.workers[sessionID] = wake
+	m.outbox.wg.Add(1)
+	go m.outboxWorker(sessionID, wake, m.outbox.stop)
+}
+
+func (m *Manager) outboxWorker(sessionID string, wake <-chan struct{}, stop <-chan struct{}) {
+	defer m.outbox.wg.Done()
+
+	for {
+		wait, idle := m.deliverNext(sessionID, stop)
+		if idle && m.outboxIdle(sessionID) {
+			return
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-wake:
+			timer.Stop()
+		case <-stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// outboxIdle encerra o registro do worker se a fila da sessão estiver vazia.
+// A verificação é feita com m.outbox.mu travado para não perder uma mensagem
+// enfileirada enquanto o worker sai.
+func (m *Manager) outboxIdle(sessionID string) bool {
+	m.outbox.mu.Lock()
+	defer m.outbox.mu.Unlock()
+
+	if msg, err := m.DB.NextOutboundMessage(sessionID); err != nil || msg != nil {
+		return false
+	}
+	delete(m.outbox.workers, sessionID)
+	return true
+}
+
+// deliverNext tenta entregar a próxima mensagem da sessão. Retorna quanto
+// esperar antes da próxima chamada e se a fila está vazia. O atraso entre
+// envios é aguardado até stop ser fechado.
+func (m *Manager) deliverNext(sessionID string, stop <-chan struct{}) (time.Duration, bool) {
+	msg, err := m.DB.NextOutboundMessage(sessionID)
+	if err != nil {
+		log.Printf("[Outbox %s] Erro ao ler fila: %v", sessionID, err)
+		return outboxPollInterval, false
+	}
+	if msg == nil {
+		return 0, true
+	}
+	if wait := time.Until(msg.NextAttemptAt); wait > 0 {
+		return wait, false
+	}
+
+	m.Mutex.Lock()
+	client, exists := m.Clients[sessionID]
+	m.Mutex.Unlock()
+	if !exists {
+		// Sessão excluída (ou pendente descartada) sem linha no banco: nada a esperar
+		if _, err := m.DB.GetSessionTenant(sessionID); err == storage.ErrSessionNotFound {
+			if n, err := m.DB.DeadOutboundForSession(sessionID, "sessão não existe mais"); err != nil {
+				log.Printf("[Outbox %s] Erro ao descartar fila: %v", sessionID, err)
+			} else {
+				log.Printf("[Outbox %s] Sessão não existe mais, %d mensagens movidas para dead-letter", sessionID, n)
+			}
+			return 0, true
+		}
+		return outboxPollInterval, false
+	}
+	if client.Status() != models.StatusConnected {
+		return outboxPollInterval, false
+	}
+
+	if err := m.DB.MarkOutboundSending(msg.ID); err != nil {
+		log.Printf("[Outbox %s] Erro ao marcar mensagem %s: %v", sessionID, msg.ID, err)
+		return outboxPollInterval, false
+	}
+
+	resp, err := client.sendText(msg.PhoneNumber, msg.Body, stop)
+	attempts := msg.Attempts + 1
+	var rateErr *RateLimitError
+	switch {
+	case err == nil:
+		sentAt := resp.Timestamp
+		if sentAt.IsZero() {
+			sentAt = time.Now()
+		}
+		client.recordSent(&models.Message{ID: msg.ID, PhoneNumber: msg.PhoneNumber, Type: messageTypeText, Body: msg.Body}, resp)
+		err = m.DB.MarkOutboundSent(msg.ID, attempts, resp.ID, sentAt)
+		log.Printf("[Outbox %s] Mensagem %s enviada (tentativa %d)", sessionID, msg.ID, attempts)
+
+	case errors.Is(err, ErrNotConnected), errors.Is(err, errShuttingDown):
+		// Não chegou a ser tentada
+		if err := m.DB.ReleaseOutbound(msg.ID); err != nil {
+			log.Printf("[Outbox %s] Erro ao devolver mensagem %s à fila: %v", sessionID, msg.ID, err)
+		}
+		return outboxPollInterval, false
+
+	case errors.As(err, &rateErr):
+		// Limite da sessão: a mensagem aguarda na fila, sem contar tentativa
+		log.Printf("[Outbox %s] %v", sessionID, rateErr)
+		if err := m.DB.ReleaseOutbound(msg.ID); err != nil {
+			log.Printf("[Outbox %s] Erro ao devolver mensagem %s à fila: %v", sessionID, msg.ID, err)
+		}
+		return rateErr.RetryAfter, false
+
+	case errors.Is(err, ErrInvalidPhone), m.Outbox.MaxAttempts > 0 && attempts >= m.Outbox.MaxAttempts:
+		log.Printf("[Outbox %s] Mensagem %s movida para dead-letter após %d tentativas: %v", sessionID, msg.ID, attempts, err)
+		err = m.DB.DeadOutbound(msg.ID, attempts, err.Error())
+
+	default:
+		delay := m.Outbox.Delay(attempts)
+		log.Printf("[Outbox %s] Falha na tentativa %d da mensagem %s, nova tentativa em %s: %v", sessionID, attempts, msg.ID, delay.Round(time.Second), err)
+		err = m.DB.RetryOutbound(msg.ID, attempts, err.Error(), time.Now().Add(delay))
+	}
+	if err != nil {
+		log.Printf("[Outbox %s] Erro ao atualizar mensagem %s: %v", sessionID, msg.ID, err)
+		return outboxPollInterval, false
+	}
+	return 0, false
+}
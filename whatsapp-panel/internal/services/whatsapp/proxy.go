@@ -0,0 +1,122 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/storage"
+)
+
+// ErrInvalidProxy indica uma URL de proxy inválida
+var ErrInvalidProxy = errors.New("proxy inválido")
+
+// NormalizeProxyURL valida a URL de proxy de uma sessão (http, https ou
+// socks5, com usuário e senha opcionais) e a retorna sem espaços. "" é
+// aceito e significa conexão direta.
+func NormalizeProxyURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidProxy, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return "", fmt.Errorf("%w: use http://, https:// ou socks5://", ErrInvalidProxy)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return "", fmt.Errorf("%w: informe host e porta", ErrInvalidProxy)
+	}
+	if u.Path != "" && u.Path != "/" || u.RawQuery != "" {
+		return "", fmt.Errorf("%w: a URL não deve ter caminho nem parâmetros", ErrInvalidProxy)
+	}
+	return raw, nil
+}
+
+// RedactProxyURL mascara a senha do proxy para exibição
+func RedactProxyURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "(inválido)"
+	}
+	return u.Redacted()
+}
+
+// Proxy retorna a URL do proxy de saída da sessão ("" = conexão direta)
+func (c *Client) Proxy() string {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	return c.proxy
+}
+
+// setProxy aplica o proxy ao transporte; vale a partir da próxima conexão
+func (c *Client) setProxy(proxyURL string) error {
+	if err := c.WAClient.SetProxyAddress(proxyURL); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProxy, err)
+	}
+	c.Mutex.Lock()
+	c.proxy = proxyURL
+	c.Mutex.Unlock()
+	return nil
+}
+
+// persistProxy grava o proxy de uma sessão recém-pareada, cuja linha acabou
+// de ser criada
+func (c *Client) persistProxy() {
+	proxyURL := c.Proxy()
+	if proxyURL == "" {
+		return
+	}
+	if err := c.DB.SetSessionProxy(c.TenantID, c.ID, proxyURL); err != nil {
+		log.Printf("[Client %s] Erro ao gravar proxy: %v", c.ID, err)
+	}
+}
+
+// SetSessionProxy altera o proxy de saída de uma sessão do tenant ("" remove).
+// O proxy é gravado no banco e aplicado ao cliente em memória; uma sessão
+// conectada é reconectada para passar a usá-lo.
+func (m *Manager) SetSessionProxy(tenantID, sessionID, proxyURL string) error {
+	proxyURL, err := NormalizeProxyURL(proxyURL)
+	if err != nil {
+		return err
+	}
+
+	client, exists := m.GetClient(tenantID, sessionID)
+
+	// Sessões pendentes ainda não têm linha; o proxy é gravado no pareamento
+	err = m.DB.SetSessionProxy(tenantID, sessionID, proxyURL)
+	if err == storage.ErrSessionNotFound && exists {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	if err := client.setProxy(proxyURL); err != nil {
+		return err
+	}
+	log.Printf("[Client %s] Proxy alterado para %q", sessionID, RedactProxyURL(proxyURL))
+
+	if client.Status() == models.StatusConnected {
+		reason := "proxy alterado"
+		// O whatsmeow não emite Disconnected em desconexões explícitas
+		client.WAClient.Disconnect()
+		client.setStatus(models.StatusDisconnected, reason)
+		client.startReconnect(reason)
+	}
+	return nil
+}
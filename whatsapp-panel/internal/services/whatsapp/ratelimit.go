@@ -0,0 +1,283 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/storage"
+)
+
+// maxSendDelay limita o atraso entre envios, que os workers da fila e das
+// campanhas aguardam antes de enviar
+const maxSendDelay = time.Minute
+
+// ErrInvalidRateLimits indica limites de envio inválidos
+var ErrInvalidRateLimits = errors.New("limites de envio inválidos")
+
+// RateLimitError indica um envio recusado por um limite da sessão. Sem
+// Window, o envio foi recusado pelo atraso mínimo desde o envio anterior.
+type RateLimitError struct {
+	Window     string // minuto, hora ou dia
+	Limit      int
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Window == "" {
+		return fmt.Sprintf("intervalo mínimo entre envios não atingido, tente novamente em %s", e.RetryAfter.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("limite de %d mensagens por %s atingido, tente novamente em %s", e.Limit, e.Window, e.RetryAfter.Round(time.Second))
+}
+
+// rateWindow é uma das janelas deslizantes de contagem de envios
+type rateWindow struct {
+	name     string
+	duration time.Duration
+	limit    func(models.RateLimits) int
+}
+
+var rateWindows = []rateWindow{
+	{"minuto", time.Minute, func(l models.RateLimits) int { return l.PerMinute }},
+	{"hora", time.Hour, func(l models.RateLimits) int { return l.PerHour }},
+	{"dia", 24 * time.Hour, func(l models.RateLimits) int { return l.PerDay }},
+}
+
+// ValidateRateLimits verifica se os limites são coerentes
+func ValidateRateLimits(l models.RateLimits) error {
+	if l.PerMinute < 0 || l.PerHour < 0 || l.PerDay < 0 || l.MinDelayMS < 0 || l.MaxDelayMS < 0 {
+		return fmt.Errorf("%w: valores não podem ser negativos", ErrInvalidRateLimits)
+	}
+	if l.MaxDelayMS < l.MinDelayMS {
+		return fmt.Errorf("%w: max_delay_ms menor que min_delay_ms", ErrInvalidRateLimits)
+	}
+	if time.Duration(l.MaxDelayMS)*time.Millisecond > maxSendDelay {
+		return fmt.Errorf("%w: atraso entre envios acima de %s", ErrInvalidRateLimits, maxSendDelay)
+	}
+	return nil
+}
+
+// RateUsage é o uso atual de cada janela de limite
+type RateUsage struct {
+	Minute int `json:"minute"`
+	Hour   int `json:"hour"`
+	Day    int `json:"day"`
+}
+
+// RateLimitInfo resume os limites de envio de uma sessão e o uso atual
+type RateLimitInfo struct {
+	Limits     models.RateLimits `json:"limits"`
+	Custom     bool              `json:"custom"` // limites próprios da sessão, e não o padrão do servidor
+	Usage      RateUsage         `json:"usage"`
+	NextSendAt *time.Time        `json:"next_send_at,omitempty"`
+}
+
+// sendLimiter aplica os limites de envio de uma sessão. Cada envio reserva um
+// horário: o atual ou, se o atraso desde o envio anterior ainda não passou, o
+// primeiro horário permitido, que o chamador aguarda antes de enviar. Um envio
+// que falha devolve a reserva.
+type sendLimiter struct {
+	mu     sync.Mutex
+	limits models.RateLimits
+	custom bool
+	sent   []time.Time // envios reservados nas últimas 24h, em ordem
+	nextAt time.Time   // primeiro horário permitido pelo atraso entre envios
+}
+
+func newSendLimiter(limits models.RateLimits, custom bool, history []time.Time) *sendLimiter {
+	return &sendLimiter{limits: limits, custom: custom, sent: history}
+}
+
+// reserve reserva o próximo envio e retorna o horário reservado, ou um
+// *RateLimitError se alguma janela estiver cheia. Sem wait, um horário
+// posterior a now também é recusado, sem reservar.
+func (l *sendLimiter) reserve(now time.Time, wait bool) (time.Time, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(now)
+	at := now
+	if l.nextAt.After(at) {
+		at = l.nextAt
+	}
+	if !wait && at.After(now) {
+		return time.Time{}, &RateLimitError{RetryAfter: at.Sub(now)}
+	}
+
+	for _, w := range rateWindows {
+		limit := w.limit(l.limits)
+		if limit <= 0 {
+			continue
+		}
+		inWindow := l.countSince(at.Add(-w.duration))
+		if inWindow >= limit {
+			oldest := l.sent[len(l.sent)-inWindow]
+			return time.Time{}, &RateLimitError{Window: w.name, Limit: limit, RetryAfter: oldest.Add(w.duration).Sub(now)}
+		}
+	}
+
+	l.sent = append(l.sent, at)
+	l.nextAt = at.Add(l.randomDelay())
+	return at, nil
+}
+
+// release devolve a reserva de um envio que não aconteceu. Se ela era a
+// última, o próximo envio pode ocupar o mesmo horário.
+func (l *sendLimiter) release(at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := len(l.sent) - 1; i >= 0; i-- {
+		if l.sent[i].Equal(at) {
+			if i == len(l.sent)-1 {
+				l.nextAt = at
+			}
+			l.sent = append(l.sent[:i], l.sent[i+1:]...)
+			return
+		}
+	}
+}
+
+// randomDelay sorteia o atraso até o próximo envio
+func (l *sendLimiter) randomDelay() time.Duration {
+	lo, hi := l.limits.MinDelayMS, l.limits.MaxDelayMS
+	delay := lo
+	if hi > lo {
+		delay += rand.Intn(hi - lo + 1)
+	}
+	return time.Duration(delay) * time.Millisecond
+}
+
+// countSince conta os envios reservados depois de since
+func (l *sendLimiter) countSince(since time.Time) int {
+	i := sort.Search(len(l.sent), func(i int) bool { return l.sent[i].After(since) })
+	return len(l.sent) - i
+}
+
+// prune descarta envios fora da maior janela
+func (l *sendLimiter) prune(now time.Time) {
+	keep := l.countSince(now.Add(-24 * time.Hour))
+	l.sent = append(l.sent[:0], l.sent[len(l.sent)-keep:]...)
+}
+
+func (l *sendLimiter) info(now time.Time) RateLimitInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(now)
+	info := RateLimitInfo{
+		Limits: l.limits,
+		Custom: l.custom,
+		Usage: RateUsage{
+			Minute: l.countSince(now.Add(-time.Minute)),
+			Hour:   l.countSince(now.Add(-time.Hour)),
+			Day:    l.countSince(now.Add(-24 * time.Hour)),
+		},
+	}
+	if l.nextAt.After(now) {
+		next := l.nextAt
+		info.NextSendAt = &next
+	}
+	return info
+}
+
+func (l *sendLimiter) setLimits(limits models.RateLimits, custom bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+	l.custom = custom
+}
+
+// RateLimits retorna os limites de envio da sessão e o uso atual de cada janela
+func (c *Client) RateLimits() RateLimitInfo {
+	return c.limiter.info(time.Now())
+}
+
+// acquireSendSlot reserva um envio dentro dos limites da sessão e retorna o
+// horário reservado, a devolver com limiter.release se o envio falhar. Os
+// workers da fila e das campanhas passam o canal de parada e aguardam o atraso
+// entre envios até ele ser fechado (errShuttingDown); requisições síncronas
+// passam nil e recebem um *RateLimitError em vez de esperar. Deve ser chamado
+// com o envio já registrado em beginSend.
+func (c *Client) acquireSendSlot(stop <-chan struct{}) (time.Time, error) {
+	now := time.Now()
+	at, err := c.limiter.reserve(now, stop != nil)
+	if err != nil || !at.After(now) {
+		return at, err
+	}
+
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return at, nil
+	case <-stop:
+		c.limiter.release(at)
+		return time.Time{}, errShuttingDown
+	}
+}
+
+// persistRateLimits grava os limites próprios de uma sessão recém-pareada,
+// cuja linha acabou de ser criada
+func (c *Client) persistRateLimits() {
+	info := c.RateLimits()
+	if !info.Custom {
+		return
+	}
+	if err := c.DB.SetSessionRateLimits(c.TenantID, c.ID, &info.Limits); err != nil {
+		log.Printf("[Client %s] Erro ao gravar limites de envio: %v", c.ID, err)
+	}
+}
+
+// newClientLimiter cria o limitador de uma sessão com os limites gravados (ou
+// o padrão do servidor) e as mensagens enviadas nas últimas 24h, para que os
+// limites valham também entre reinícios
+func (m *Manager) newClientLimiter(sessionID string) *sendLimiter {
+	limits, custom := m.RateLimits, false
+	stored, err := m.DB.GetSessionRateLimits(sessionID)
+	if err != nil && err != storage.ErrSessionNotFound {
+		log.Printf("[Client %s] Erro ao ler limites de envio, usando o padrão: %v", sessionID, err)
+	}
+	if stored != nil {
+		limits, custom = *stored, true
+	}
+
+	history, err := m.DB.ListSentSince(sessionID, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		log.Printf("[Client %s] Erro ao ler envios recentes: %v", sessionID, err)
+	}
+	return newSendLimiter(limits, custom, history)
+}
+
+// SetSessionRateLimits altera os limites de envio de uma sessão do tenant;
+// nil volta ao padrão do servidor. Sessões pendentes gravam os limites no pareamento.
+func (m *Manager) SetSessionRateLimits(tenantID, sessionID string, limits *models.RateLimits) error {
+	if limits != nil {
+		if err := ValidateRateLimits(*limits); err != nil {
+			return err
+		}
+	}
+
+	client, exists := m.GetClient(tenantID, sessionID)
+	err := m.DB.SetSessionRateLimits(tenantID, sessionID, limits)
+	if err == storage.ErrSessionNotFound && exists {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		if limits == nil {
+			client.limiter.setLimits(m.RateLimits, false)
+		} else {
+			client.limiter.setLimits(*limits, true)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package whatsapp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"whatsapp-panel/internal/models"
+)
+
+func TestSendLimiterWindows(t *testing.T) {
+	l := newSendLimiter(models.RateLimits{PerMinute: 2, PerHour: 3}, false, nil)
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if _, err := l.reserve(start.Add(time.Duration(i)*time.Second), false); err != nil {
+			t.Fatalf("envio %d: %v", i+1, err)
+		}
+	}
+
+	// Terceiro envio no mesmo minuto: libera quando o primeiro sair da janela
+	_, err := l.reserve(start.Add(10*time.Second), false)
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("terceiro envio = %v, quer *RateLimitError", err)
+	}
+	if rateErr.Window != "minuto" || rateErr.Limit != 2 || rateErr.RetryAfter != 50*time.Second {
+		t.Errorf("erro = %+v, quer minuto/2/50s", rateErr)
+	}
+
+	if _, err := l.reserve(start.Add(time.Minute+time.Second), false); err != nil {
+		t.Fatalf("envio após a janela do minuto: %v", err)
+	}
+
+	// Limite da hora: o quarto envio espera o primeiro completar uma hora
+	_, err = l.reserve(start.Add(3*time.Minute), false)
+	if !errors.As(err, &rateErr) || rateErr.Window != "hora" {
+		t.Fatalf("quarto envio = %v, quer limite da hora", err)
+	}
+	if want := time.Hour - 3*time.Minute; rateErr.RetryAfter != want {
+		t.Errorf("RetryAfter = %s, quer %s", rateErr.RetryAfter, want)
+	}
+
+	info := l.info(start.Add(3 * time.Minute))
+	if info.Usage != (RateUsage{Minute: 0, Hour: 3, Day: 3}) {
+		t.Errorf("uso = %+v", info.Usage)
+	}
+}
+
+func TestSendLimiterHistory(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	history := []time.Time{now.Add(-25 * time.Hour), now.Add(-2 * time.Hour), now.Add(-30 * time.Second)}
+	l := newSendLimiter(models.RateLimits{PerMinute: 1, PerDay: 3}, false, history)
+
+	_, err := l.reserve(now, false)
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) || rateErr.Window != "minuto" || rateErr.RetryAfter != 30*time.Second {
+		t.Fatalf("envio com histórico = %v, quer limite do minuto em 30s", err)
+	}
+	if usage := l.info(now).Usage; usage.Day != 2 {
+		t.Errorf("uso no dia = %d, quer 2 (envio de 25h atrás fora da janela)", usage.Day)
+	}
+}
+
+func TestSendLimiterDelay(t *testing.T) {
+	l := newSendLimiter(models.RateLimits{MinDelayMS: 2000, MaxDelayMS: 2000}, false, nil)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	if at, err := l.reserve(now, false); err != nil || !at.Equal(now) {
+		t.Fatalf("primeiro envio = %s, %v", at, err)
+	}
+
+	// Sem espera, o atraso pendente é recusado e nada é reservado
+	_, err := l.reserve(now.Add(500*time.Millisecond), false)
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) || rateErr.Window != "" || rateErr.RetryAfter != 1500*time.Millisecond {
+		t.Fatalf("envio sem espera = %v, quer atraso de 1.5s", err)
+	}
+	if usage := l.info(now).Usage; usage.Minute != 1 {
+		t.Errorf("uso = %d, quer 1", usage.Minute)
+	}
+
+	// Com espera, o envio é reservado para o fim do atraso
+	at, err := l.reserve(now.Add(500*time.Millisecond), true)
+	if err != nil || !at.Equal(now.Add(2*time.Second)) {
+		t.Fatalf("envio com espera = %s, %v", at, err)
+	}
+}
+
+func TestSendLimiterRelease(t *testing.T) {
+	l := newSendLimiter(models.RateLimits{PerMinute: 1, MinDelayMS: 5000, MaxDelayMS: 5000}, false, nil)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	at, err := l.reserve(now, false)
+	if err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	// Envio que falhou não conta no limite nem adia o próximo
+	l.release(at)
+	if usage := l.info(now).Usage; usage.Minute != 0 {
+		t.Errorf("uso após devolver = %d, quer 0", usage.Minute)
+	}
+	if _, err := l.reserve(now.Add(time.Second), false); err != nil {
+		t.Errorf("envio após devolver a reserva: %v", err)
+	}
+}
+
+func TestSendFailureReleasesSlot(t *testing.T) {
+	m := newTestManager(t)
+	m.RateLimits = models.RateLimits{PerMinute: 1}
+
+	client, transport := pairTestClient(t, m)
+	transport.SendErr = errors.New("falha no envio")
+	if err := client.SendTextMessage("5511987654321", "oi"); err == nil {
+		t.Fatal("envio com falha retornou nil")
+	}
+	transport.SendErr = nil
+	if err := client.SendTextMessage("5511987654321", "oi"); err != nil {
+		t.Fatalf("envio depois da falha: %v", err)
+	}
+
+	var rateErr *RateLimitError
+	if err := client.SendTextMessage("5511987654321", "oi"); !errors.As(err, &rateErr) {
+		t.Fatalf("segundo envio no minuto = %v, quer *RateLimitError", err)
+	}
+}
+
+func TestSendSlotWaitStops(t *testing.T) {
+	m := newTestManager(t)
+	m.RateLimits = models.RateLimits{MinDelayMS: 60000, MaxDelayMS: 60000}
+
+	client, _ := pairTestClient(t, m)
+	if err := client.SendTextMessage("5511987654321", "oi"); err != nil {
+		t.Fatalf("primeiro envio: %v", err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.sendText("5511987654321", "oi", stop)
+		done <- err
+	}()
+	close(stop)
+	select {
+	case err := <-done:
+		if !errors.Is(err, errShuttingDown) {
+			t.Errorf("envio interrompido = %v, quer errShuttingDown", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("espera pelo atraso entre envios não foi interrompida")
+	}
+	if usage := client.RateLimits().Usage; usage.Minute != 1 {
+		t.Errorf("uso = %d, quer 1 (reserva interrompida devolvida)", usage.Minute)
+	}
+}
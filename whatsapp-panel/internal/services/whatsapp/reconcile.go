@@ -0,0 +1,253 @@
+package whatsapp
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+
+	"whatsapp-panel/internal/models"
+)
+
+// ReconcilePolicy configura o reconciliador de sessões, que substitui a
+// limpeza por cron: remove apenas clientes, arquivos e linhas órfãos
+type ReconcilePolicy struct {
+	Interval time.Duration // intervalo entre execuções (0 desativa o reconciliador)
+	Grace    time.Duration // idade mínima de clientes pendentes e arquivos antes de serem considerados órfãos
+	DryRun   bool          // apenas registra na auditoria o que seria removido
+}
+
+// ReconcileReport resume uma execução do reconciliador
+type ReconcileReport struct {
+	RunID     string                   `json:"run_id"`
+	DryRun    bool                     `json:"dry_run"`
+	StartedAt time.Time                `json:"started_at"`
+	Actions   []models.ReconcileAction `json:"actions"`
+	Errors    []string                 `json:"errors"`
+	// Unregistered lista os arquivos com dispositivo pareado sem sessão
+	// cadastrada, que nunca são removidos automaticamente
+	Unregistered []string `json:"unregistered"`
+}
+
+// StartReconciler inicia a execução periódica do reconciliador. Deve ser
+// chamado depois de RestoreSessions, para que as sessões restauradas já
+// estejam no gerenciador.
+func (m *Manager) StartReconciler() {
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+
+	if m.Reconciler.Interval <= 0 || m.reconcileStop != nil {
+		return
+	}
+	m.reconcileStop = make(chan struct{})
+	go m.reconcileLoop(m.Reconciler.Interval, m.reconcileStop)
+
+	mode := "ativo"
+	if m.Reconciler.DryRun {
+		mode = "simulação"
+	}
+	log.Printf("[Reconcile] Reconciliador iniciado (a cada %s, modo %s)", m.Reconciler.Interval, mode)
+}
+
+// StopReconciler encerra a execução periódica do reconciliador
+func (m *Manager) StopReconciler() {
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+
+	if m.reconcileStop != nil {
+		close(m.reconcileStop)
+		m.reconcileStop = nil
+	}
+}
+
+func (m *Manager) reconcileLoop(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := m.Reconcile(m.Reconciler.DryRun); err != nil {
+				log.Printf("[Reconcile] Erro na reconciliação: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Reconcile remove o que estiver órfão: clientes pendentes além do prazo,
+// arquivos sem dispositivo pareado e linhas sem arquivo. Sessões em uso no
+// gerenciador ou abertas por outro processo nunca são tocadas; arquivos com
+// dispositivo pareado mas sem linha (ex: pareados por uma ferramenta com outro
+// banco) e arquivos que não puderem ser lidos (ex: cifrados com outra chave)
+// são apenas reportados. Linhas de sessões deslogadas guardam o histórico e
+// nunca são removidas, e a linha de um arquivo removido só é considerada
+// órfã em uma execução seguinte. Toda ação, executada ou simulada, é gravada
+// em reconcile_audit.
+func (m *Manager) Reconcile(dryRun bool) (*ReconcileReport, error) {
+	// Serializar com a criação e a importação de sessões, que gravam o
+	// arquivo antes da linha
+	m.createMu.Lock()
+	defer m.createMu.Unlock()
+
+	now := time.Now()
+	report := &ReconcileReport{
+		RunID:     uuid.New().String(),
+		DryRun:    dryRun,
+		StartedAt: now,
+		Actions:   []models.ReconcileAction{},
+		Errors:    []string{},
+
+		Unregistered: []string{},
+	}
+	grace := m.Reconciler.Grace
+
+	m.Mutex.Lock()
+	clients := make(map[string]*Client, len(m.Clients))
+	for id, client := range m.Clients {
+		clients[id] = client
+	}
+	m.Mutex.Unlock()
+
+	// 1. Clientes aguardando pareamento além do prazo
+	live := make(map[string]bool, len(clients))
+	for id, client := range clients {
+		if client.Status() != models.StatusPending || now.Sub(client.StatusChangedAt()) < grace {
+			live[id] = true
+			continue
+		}
+		removed := m.reconcileAction(report, models.ReconcileRemovePendingClient, id, client.TenantID,
+			fmt.Sprintf("aguardando pareamento há mais de %s", grace), func() error {
+				m.RemoveClient(id)
+				return m.removeSessionFile(id)
+			})
+		if !removed || dryRun {
+			live[id] = true
+		}
+	}
+
+	rows, err := m.DB.ListSessionRefs()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar sessões: %v", err)
+	}
+
+	// 2. Arquivos sem dispositivo pareado (os sem linha são apenas reportados)
+	files, err := m.Sessions.Files()
+	if err != nil {
+		return nil, err
+	}
+	withFile := make(map[string]bool, len(files))
+	for _, file := range files {
+		id := m.Sessions.SessionID(file)
+		withFile[id] = true
+		if _, err := uuid.Parse(id); err != nil || live[id] {
+			continue
+		}
+
+		// Sessões abertas por outro processo (outro servidor ou ferramenta) não são órfãs
+		owner, err := m.Sessions.Owner(id)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		if owner != nil {
+			continue
+		}
+
+		info, err := os.Stat(m.Sessions.Path(id))
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		if now.Sub(info.ModTime()) < grace {
+			continue
+		}
+
+		jid, err := m.Sessions.DeviceJID(id)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+
+		if jid != "" {
+			if _, ok := rows[id]; !ok {
+				report.Unregistered = append(report.Unregistered, file)
+			}
+			continue
+		}
+		m.reconcileAction(report, models.ReconcileRemoveFileWithoutDevice, id, rows[id].TenantID, "arquivo sem dispositivo pareado", func() error {
+			return m.removeSessionFile(id)
+		})
+	}
+
+	// 3. Linhas sem arquivo de sessão. Se nenhuma linha tem arquivo, o
+	// diretório de sessões provavelmente está errado ou vazio: não apagar nada
+	var missing []string
+	for id := range rows {
+		if !withFile[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 && len(missing) == len(rows) {
+		report.Errors = append(report.Errors, fmt.Sprintf("nenhuma das %d sessões cadastradas tem arquivo em %s; linhas mantidas", len(rows), m.Sessions.Dir))
+		missing = nil
+	}
+	for _, id := range missing {
+		row := rows[id]
+		if live[id] || row.Status == models.StatusLoggedOut {
+			continue
+		}
+		m.reconcileAction(report, models.ReconcileRemoveRowWithoutFile, id, row.TenantID, "arquivo de sessão ausente", func() error {
+			return m.DB.DeleteSession(row.TenantID, id)
+		})
+	}
+
+	if len(report.Actions) > 0 || len(report.Errors) > 0 {
+		log.Printf("[Reconcile] Execução %s: %d ações, %d erros (simulação: %t)", report.RunID, len(report.Actions), len(report.Errors), dryRun)
+	}
+	if len(report.Unregistered) > 0 {
+		log.Printf("[Reconcile] ⚠️ %d arquivos com dispositivo pareado sem sessão cadastrada (não removidos): %v", len(report.Unregistered), report.Unregistered)
+	}
+	return report, nil
+}
+
+// reconcileAction executa (fora do modo de simulação) e audita uma ação,
+// informando se ela foi (ou, na simulação, seria) concluída
+func (m *Manager) reconcileAction(report *ReconcileReport, action, sessionID, tenantID, reason string, apply func() error) bool {
+	a := models.ReconcileAction{
+		RunID:     report.RunID,
+		Action:    action,
+		SessionID: sessionID,
+		TenantID:  tenantID,
+		Reason:    reason,
+		DryRun:    report.DryRun,
+		CreatedAt: time.Now(),
+	}
+
+	if report.DryRun {
+		log.Printf("[Reconcile] (simulação) %s %s: %s", action, sessionID, reason)
+	} else if err := apply(); err != nil {
+		a.Error = err.Error()
+		report.Errors = append(report.Errors, fmt.Sprintf("%s %s: %v", action, sessionID, err))
+		log.Printf("[Reconcile] Erro em %s %s: %v", action, sessionID, err)
+	} else {
+		log.Printf("[Reconcile] %s %s: %s", action, sessionID, reason)
+	}
+
+	if err := m.DB.RecordReconcileAction(&a); err != nil {
+		log.Printf("[Reconcile] Erro ao gravar auditoria de %s %s: %v", action, sessionID, err)
+	}
+	report.Actions = append(report.Actions, a)
+	return a.Error == ""
+}
+
+// removeSessionFile apaga o arquivo da sessão, ignorando arquivos já ausentes
+func (m *Manager) removeSessionFile(sessionID string) error {
+	if err := m.Sessions.Remove(sessionID); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package whatsapp
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-panel/internal/models"
+)
+
+// reconcileActions conta as ações de uma execução por tipo
+func reconcileActions(report *ReconcileReport) map[string]int {
+	counts := make(map[string]int)
+	for _, a := range report.Actions {
+		counts[a.Action]++
+	}
+	return counts
+}
+
+// detachTestSession pareia uma sessão, tira o cliente do gerenciador e apaga
+// o arquivo, deixando só a linha no banco
+func detachTestSession(t *testing.T, m *Manager) string {
+	t.Helper()
+	client, _ := pairTestClient(t, m)
+	m.RemoveClient(client.ID)
+	if err := m.Sessions.Remove(client.ID); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	return client.ID
+}
+
+func TestReconcileKeepsLoggedOutSession(t *testing.T) {
+	m := newTestManager(t)
+	m.Reconnect.InitialDelay = time.Hour
+	m.Reconciler.Grace = 0
+
+	pairTestClient(t, m) // sessão em uso, com arquivo
+	client, transport := pairTestClient(t, m)
+	transport.EmitLoggedOut(events.ConnectFailureLoggedOut)
+	waitFor(t, "remoção do cliente deslogado", func() bool {
+		_, exists := m.GetClient(models.DefaultTenantID, client.ID)
+		return !exists
+	})
+
+	// O arquivo ficou sem dispositivo e é removido; a linha não, nem nesta
+	// execução nem na seguinte
+	for run := 1; run <= 2; run++ {
+		report, err := m.Reconcile(false)
+		if err != nil {
+			t.Fatalf("Reconcile (execução %d): %v", run, err)
+		}
+		if n := reconcileActions(report)[models.ReconcileRemoveRowWithoutFile]; n != 0 {
+			t.Errorf("execução %d removeu %d linhas, quer 0", run, n)
+		}
+		if _, err := m.DB.GetSession(models.DefaultTenantID, client.ID); err != nil {
+			t.Fatalf("sessão deslogada após a execução %d: %v", run, err)
+		}
+	}
+	if _, err := os.Stat(m.Sessions.Path(client.ID)); !os.IsNotExist(err) {
+		t.Error("arquivo sem dispositivo não foi removido")
+	}
+}
+
+func TestReconcileRemovesRowWithoutFile(t *testing.T) {
+	m := newTestManager(t)
+	m.Reconciler.Grace = 0
+
+	pairTestClient(t, m)
+	orphan := detachTestSession(t, m)
+
+	report, err := m.Reconcile(false)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if n := reconcileActions(report)[models.ReconcileRemoveRowWithoutFile]; n != 1 {
+		t.Fatalf("linhas removidas = %d, quer 1", n)
+	}
+	if _, err := m.DB.GetSession(models.DefaultTenantID, orphan); err == nil {
+		t.Error("linha sem arquivo não foi removida")
+	}
+}
+
+func TestReconcileKeepsRowsWhenNoRowHasFile(t *testing.T) {
+	m := newTestManager(t)
+	m.Reconciler.Grace = 0
+
+	// Como um SESSIONS_DIR errado ou vazio: nenhuma linha tem arquivo
+	first := detachTestSession(t, m)
+	second := detachTestSession(t, m)
+
+	report, err := m.Reconcile(false)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if len(report.Actions) != 0 {
+		t.Errorf("ações = %v, quer nenhuma", report.Actions)
+	}
+	if len(report.Errors) == 0 {
+		t.Error("execução não reportou o diretório sem arquivos")
+	}
+	for _, id := range []string{first, second} {
+		if _, err := m.DB.GetSession(models.DefaultTenantID, id); err != nil {
+			t.Errorf("sessão %s removida: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package whatsapp
+
+import (
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ReconnectPolicy configura a reconexão automática de uma sessão
+type ReconnectPolicy struct {
+	InitialDelay time.Duration // atraso antes da primeira tentativa
+	MaxDelay     time.Duration // limite superior do atraso entre tentativas
+	Multiplier   float64       // fator de crescimento do atraso a cada tentativa
+	Jitter       float64       // fração do atraso sorteada para mais ou para menos (0.2 = ±20%)
+	MaxAttempts  int           // número máximo de tentativas (0 = sem limite)
+}
+
+// DefaultReconnectPolicy retorna a política usada quando nada é configurado
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return ReconnectPolicy{
+		InitialDelay: 2 * time.Second,
+		MaxDelay:     5 * time.Minute,
+		Multiplier:   2,
+		Jitter:       0.2,
+		MaxAttempts:  10,
+	}
+}
+
+// Delay retorna o atraso antes da tentativa attempt (a partir de 1), já com jitter
+func (p ReconnectPolicy) Delay(attempt int) time.Duration {
+	delay := float64(p.InitialDelay) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(delay)
+}
+
+// maxReconnectHistory limita quantas tentativas ficam guardadas por sessão
+const maxReconnectHistory = 20
+
+// ReconnectAttempt registra uma tentativa de reconexão e seu resultado
+type ReconnectAttempt struct {
+	Attempt      int       `json:"attempt"`
+	StartedAt    time.Time `json:"started_at"`
+	DelaySeconds float64   `json:"delay_seconds"`
+	Success      bool      `json:"success"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// ReconnectInfo resume o estado da reconexão automática de uma sessão
+type ReconnectInfo struct {
+	Active        bool               `json:"active"`
+	Attempt       int                `json:"attempt"`
+	MaxAttempts   int                `json:"max_attempts"`
+	NextAttemptAt *time.Time         `json:"next_attempt_at,omitempty"`
+	Stopped       bool               `json:"stopped"`
+	StopReason    string             `json:"stop_reason,omitempty"`
+	Attempts      []ReconnectAttempt `json:"attempts"`
+}
+
+// reconnectState guarda o estado do loop de reconexão de um Client
+type reconnectState struct {
+	mu        sync.Mutex
+	policy    ReconnectPolicy
+	stop      chan struct{}
+	permanent bool
+	info      ReconnectInfo
+}
+
+// ReconnectInfo retorna uma cópia do estado atual da reconexão automática
+func (c *Client) ReconnectInfo() ReconnectInfo {
+	c.reconnect.mu.Lock()
+	defer c.reconnect.mu.Unlock()
+
+	info := c.reconnect.info
+	info.MaxAttempts = c.reconnect.policy.MaxAttempts
+	info.Attempts = append([]ReconnectAttempt{}, c.reconnect.info.Attempts...)
+	return info
+}
+
+// startReconnect inicia o loop de reconexão supervisionada, se ainda não
+// estiver ativo e se a sessão não tiver sido encerrada permanentemente
+func (c *Client) startReconnect(reason string) {
+	c.reconnect.mu.Lock()
+	defer c.reconnect.mu.Unlock()
+
+	if c.reconnect.permanent || c.reconnect.info.Active {
+		return
+	}
+	// Sessões ainda não pareadas não têm para onde reconectar
+	if c.WAClient.DeviceID() == nil {
+		return
+	}
+
+	c.reconnect.stop = make(chan struct{})
+	c.reconnect.info.Active = true
+	c.reconnect.info.Attempt = 0
+	c.reconnect.info.Stopped = false
+	c.reconnect.info.StopReason = ""
+
+	log.Printf("[Reconnect %s] Iniciando reconexão automática: %s", c.ID, reason)
+	go c.reconnectLoop(c.reconnect.policy, c.reconnect.stop)
+}
+
+// stopReconnect interrompe o loop de reconexão. Se permanent for true, a
+// sessão não volta a ser reconectada automaticamente (logout, banimento).
+func (c *Client) stopReconnect(reason string, permanent bool) {
+	c.reconnect.mu.Lock()
+	defer c.reconnect.mu.Unlock()
+
+	if permanent {
+		c.reconnect.permanent = true
+	}
+	if c.reconnect.info.Active {
+		close(c.reconnect.stop)
+		c.reconnect.info.Active = false
+		c.reconnect.info.NextAttemptAt = nil
+	}
+	if permanent || c.reconnect.info.Attempt > 0 {
+		c.reconnect.info.Stopped = true
+		c.reconnect.info.StopReason = reason
+		log.Printf("[Reconnect %s] Reconexão automática interrompida: %s", c.ID, reason)
+	}
+}
+
+func (c *Client) reconnectLoop(policy ReconnectPolicy, stop <-chan struct{}) {
+	for attempt := 1; policy.MaxAttempts == 0 || attempt <= policy.MaxAttempts; attempt++ {
+		delay := policy.Delay(attempt)
+		next := time.Now().Add(delay)
+
+		c.reconnect.mu.Lock()
+		c.reconnect.info.Attempt = attempt
+		c.reconnect.info.NextAttemptAt = &next
+		c.reconnect.mu.Unlock()
+
+		log.Printf("[Reconnect %s] Tentativa %d em %s", c.ID, attempt, delay.Round(time.Millisecond))
+
+		select {
+		case <-time.After(delay):
+		case <-stop:
+			return
+		}
+
+		result := ReconnectAttempt{
+			Attempt:      attempt,
+			StartedAt:    time.Now(),
+			DelaySeconds: delay.Seconds(),
+		}
+
+		err := c.Connect()
+
+		select {
+		case <-stop:
+			// Interrompido durante a tentativa (logout, banimento ou desconexão manual)
+			return
+		default:
+		}
+
+		result.Success = err == nil
+		if err != nil {
+			result.Error = err.Error()
+			log.Printf("[Reconnect %s] Tentativa %d falhou: %v", c.ID, attempt, err)
+		}
+
+		c.reconnect.mu.Lock()
+		c.reconnect.info.Attempts = append(c.reconnect.info.Attempts, result)
+		if len(c.reconnect.info.Attempts) > maxReconnectHistory {
+			c.reconnect.info.Attempts = c.reconnect.info.Attempts[len(c.reconnect.info.Attempts)-maxReconnectHistory:]
+		}
+		c.reconnect.info.NextAttemptAt = nil
+		if result.Success {
+			c.reconnect.info.Active = false
+		}
+		c.reconnect.mu.Unlock()
+
+		if result.Success {
+			log.Printf("[Reconnect %s] Reconectado na tentativa %d", c.ID, attempt)
+			return
+		}
+	}
+
+	c.reconnect.mu.Lock()
+	c.reconnect.info.Active = false
+	c.reconnect.info.Stopped = true
+	c.reconnect.info.StopReason = "limite de tentativas atingido"
+	c.reconnect.mu.Unlock()
+
+	log.Printf("[Reconnect %s] Limite de %d tentativas atingido, desistindo", c.ID, policy.MaxAttempts)
+}
@@ -0,0 +1,95 @@
+package whatsapp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-panel/internal/models"
+)
+
+func TestReconnectPolicyDelay(t *testing.T) {
+	p := ReconnectPolicy{InitialDelay: time.Second, MaxDelay: 10 * time.Second, Multiplier: 2}
+	for attempt, want := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 8 * time.Second,
+		5: 10 * time.Second,
+		9: 10 * time.Second,
+	} {
+		if got := p.Delay(attempt); got != want {
+			t.Errorf("Delay(%d) = %s, quer %s", attempt, got, want)
+		}
+	}
+
+	p.Jitter = 0.2
+	for i := 0; i < 100; i++ {
+		got := p.Delay(3)
+		if got < 3200*time.Millisecond || got > 4800*time.Millisecond {
+			t.Fatalf("Delay(3) com jitter de 20%% = %s, fora de 3.2s..4.8s", got)
+		}
+	}
+}
+
+func TestReconnectRetriesWithBackoff(t *testing.T) {
+	m := newTestManager(t)
+	m.Reconnect = ReconnectPolicy{InitialDelay: 10 * time.Millisecond, MaxDelay: 40 * time.Millisecond, Multiplier: 2, MaxAttempts: 4}
+
+	client, transport := pairTestClient(t, m)
+	transport.ConnectErr = errors.New("rede indisponível")
+	transport.DropConnection()
+	transport.Emit(&events.Disconnected{})
+
+	waitFor(t, "fim das tentativas", func() bool {
+		info := client.ReconnectInfo()
+		return info.Stopped && !info.Active
+	})
+	info := client.ReconnectInfo()
+	if len(info.Attempts) != 4 {
+		t.Fatalf("tentativas = %d, quer 4", len(info.Attempts))
+	}
+	for i, attempt := range info.Attempts {
+		if attempt.Success || attempt.Error == "" {
+			t.Errorf("tentativa %d: sucesso inesperado", attempt.Attempt)
+		}
+		if want := m.Reconnect.Delay(i + 1).Seconds(); attempt.DelaySeconds != want {
+			t.Errorf("tentativa %d: atraso %vs, quer %vs", attempt.Attempt, attempt.DelaySeconds, want)
+		}
+	}
+	if info.StopReason != "limite de tentativas atingido" {
+		t.Errorf("motivo = %q", info.StopReason)
+	}
+}
+
+func TestReconnectStopsOnLoggedOut(t *testing.T) {
+	m := newTestManager(t)
+	m.Reconnect = ReconnectPolicy{InitialDelay: time.Hour, Multiplier: 2}
+
+	client, transport := pairTestClient(t, m)
+	transport.DropConnection()
+	transport.Emit(&events.Disconnected{})
+	if info := client.ReconnectInfo(); !info.Active {
+		t.Fatal("reconexão não iniciou após a desconexão")
+	}
+
+	transport.EmitLoggedOut(events.ConnectFailureLoggedOut)
+	info := client.ReconnectInfo()
+	if info.Active || !info.Stopped {
+		t.Fatalf("reconexão ativa após logout: %+v", info)
+	}
+	if info.StopReason != "sessão deslogada" {
+		t.Errorf("motivo = %q, quer %q", info.StopReason, "sessão deslogada")
+	}
+
+	// Logout é permanente: uma nova desconexão não reinicia a reconexão
+	client.startReconnect("teste")
+	if client.ReconnectInfo().Active {
+		t.Error("reconexão reiniciada depois do logout")
+	}
+	if got := client.Status(); got != models.StatusLoggedOut {
+		t.Errorf("status = %s, quer %s", got, models.StatusLoggedOut)
+	}
+}
@@ -0,0 +1,380 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // fusos horários embutidos, para servidores sem /usr/share/zoneinfo
+
+	"github.com/google/uuid"
+
+	"whatsapp-panel/internal/models"
+	"whatsapp-panel/internal/storage"
+)
+
+// Agendamentos: envios únicos (send_at) ou recorrentes (cron), sempre num fuso
+// explícito. Um único agendador percorre os agendamentos vencidos e coloca a
+// mensagem na fila de envio da sessão, que cuida das tentativas e dos limites.
+// Cada execução é registrada no banco antes do enfileiramento, então um
+// reinício nunca repete uma execução. Execuções vencidas com o servidor parado
+// são feitas ao iniciar, uma só por agendamento: a próxima de um recorrente é
+// calculada a partir da hora atual.
+
+// scheduleMaxWait limita a espera do agendador entre duas verificações do banco
+const scheduleMaxWait = time.Minute
+
+var (
+	// ErrInvalidSchedule indica dados inválidos na criação de um agendamento
+	ErrInvalidSchedule = errors.New("agendamento inválido")
+	// ErrScheduleState indica uma operação não permitida no estado atual do agendamento
+	ErrScheduleState = errors.New("operação não permitida no estado atual do agendamento")
+)
+
+// scheduleLocalLayouts são os formatos aceitos em send_at sem fuso, que é
+// então o do agendamento
+var scheduleLocalLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// scheduler guarda o estado do agendador
+type scheduler struct {
+	mu   sync.Mutex
+	stop chan struct{} // nil antes de StartScheduler e depois de StopScheduler
+	wake chan struct{} // sinal de agendamento criado ou cancelado
+	wg   sync.WaitGroup
+}
+
+// StartScheduler inicia o agendador. Deve ser chamado depois de
+// RestoreSessions e StartOutbox.
+func (m *Manager) StartScheduler() error {
+	m.schedules.mu.Lock()
+	defer m.schedules.mu.Unlock()
+
+	if m.schedules.stop != nil {
+		return nil
+	}
+	due, err := m.DB.ListDueSchedules(time.Now())
+	if err != nil {
+		return err
+	}
+	m.schedules.stop = make(chan struct{})
+	m.schedules.wake = make(chan struct{}, 1)
+	m.schedules.wg.Add(1)
+	go m.scheduleLoop(m.schedules.wake, m.schedules.stop)
+	log.Printf("[Schedule] Agendador iniciado (%d agendamentos vencidos)", len(due))
+	return nil
+}
+
+// StopScheduler encerra o agendador e aguarda a execução em andamento, até o
+// prazo de ctx
+func (m *Manager) StopScheduler(ctx context.Context) {
+	m.schedules.mu.Lock()
+	if m.schedules.stop == nil {
+		m.schedules.mu.Unlock()
+		return
+	}
+	close(m.schedules.stop)
+	m.schedules.stop = nil
+	m.schedules.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.schedules.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("[Schedule] Prazo para encerrar o agendador expirado")
+	}
+}
+
+// ScheduleRequest são os dados de um novo agendamento. A mensagem vem de
+// Message ou do modelo TemplateID; o envio é único em SendAt (RFC 3339, ou
+// data e hora sem fuso, interpretadas em Timezone) ou recorrente em Cron.
+type ScheduleRequest struct {
+	PhoneNumber string
+	Message     string
+	TemplateID  string
+	Variables   map[string]string
+	SendAt      string
+	Cron        string
+	Timezone    string
+}
+
+// CreateSchedule valida e grava um agendamento da sessão do tenant. Com
+// modelo, a mensagem é renderizada na hora de cada envio, mas as variáveis já
+// são conferidas aqui.
+func (m *Manager) CreateSchedule(tenantID, sessionID string, req ScheduleRequest) (*models.ScheduledMessage, error) {
+	client, exists := m.GetClient(tenantID, sessionID)
+	if !exists {
+		return nil, storage.ErrSessionNotFound
+	}
+	if _, err := recipientJID(req.PhoneNumber); err != nil {
+		return nil, err
+	}
+
+	if (strings.TrimSpace(req.Message) == "") == (req.TemplateID == "") {
+		return nil, fmt.Errorf("%w: informe \"message\" ou \"template_id\"", ErrInvalidSchedule)
+	}
+	variables := MergeVariables(req.Variables)
+	if req.TemplateID != "" {
+		if _, err := m.RenderTemplate(tenantID, req.TemplateID, client.ContactVariables(req.PhoneNumber), variables); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Timezone == "" || req.Timezone == "Local" {
+		return nil, fmt.Errorf("%w: informe o fuso horário em \"timezone\" (ex: America/Sao_Paulo)", ErrInvalidSchedule)
+	}
+	loc, err := time.LoadLocation(req.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: fuso horário %q desconhecido", ErrInvalidSchedule, req.Timezone)
+	}
+
+	s := &models.ScheduledMessage{
+		ID:          uuid.New().String(),
+		SessionID:   sessionID,
+		TenantID:    tenantID,
+		PhoneNumber: req.PhoneNumber,
+		Body:        req.Message,
+		TemplateID:  req.TemplateID,
+		Variables:   variables,
+		Timezone:    loc.String(),
+		Status:      models.ScheduleActive,
+	}
+	if len(s.Variables) == 0 {
+		s.Variables = nil
+	}
+
+	now := time.Now()
+	switch {
+	case (req.SendAt == "") == (req.Cron == ""):
+		return nil, fmt.Errorf("%w: informe \"send_at\" ou \"cron\"", ErrInvalidSchedule)
+	case req.SendAt != "":
+		sendAt, err := parseSendAt(req.SendAt, loc)
+		if err != nil {
+			return nil, err
+		}
+		if !sendAt.After(now) {
+			return nil, fmt.Errorf("%w: send_at já passou (%s)", ErrInvalidSchedule, sendAt.Format(time.RFC3339))
+		}
+		s.SendAt, s.NextRunAt = &sendAt, &sendAt
+	default:
+		cron, err := ParseCron(req.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+		}
+		next := cron.Next(now.In(loc))
+		if next.IsZero() {
+			return nil, fmt.Errorf("%w: a expressão cron %q não tem execução nos próximos anos", ErrInvalidSchedule, req.Cron)
+		}
+		s.Cron, s.NextRunAt = strings.TrimSpace(req.Cron), &next
+	}
+
+	if err := m.DB.CreateSchedule(s); err != nil {
+		return nil, err
+	}
+	log.Printf("[Schedule %s] Agendamento criado na sessão %s para %s (próximo envio: %s)", s.ID, sessionID, s.PhoneNumber, s.NextRunAt.Format(time.RFC3339))
+	m.wakeScheduler()
+	return s, nil
+}
+
+// parseSendAt interpreta send_at em RFC 3339 ou, sem fuso, no fuso loc
+func parseSendAt(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), nil
+	}
+	for _, layout := range scheduleLocalLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: send_at %q inválido (use RFC 3339 ou AAAA-MM-DDTHH:MM)", ErrInvalidSchedule, value)
+}
+
+// GetSchedule retorna um agendamento da sessão do tenant
+func (m *Manager) GetSchedule(tenantID, sessionID, scheduleID string) (*models.ScheduledMessage, error) {
+	s, err := m.DB.GetSchedule(tenantID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if s.SessionID != sessionID {
+		return nil, storage.ErrScheduleNotFound
+	}
+	return s, nil
+}
+
+// CancelSchedule cancela um agendamento ativo; execuções já enfileiradas não
+// são afetadas
+func (m *Manager) CancelSchedule(tenantID, sessionID, scheduleID string) (*models.ScheduledMessage, error) {
+	s, err := m.GetSchedule(tenantID, sessionID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if s.Status != models.ScheduleActive {
+		return nil, fmt.Errorf("%w: o agendamento está %s", ErrScheduleState, scheduleStatusLabel(s.Status))
+	}
+	if err := m.DB.CancelSchedule(tenantID, scheduleID); err != nil {
+		if errors.Is(err, storage.ErrScheduleNotFound) {
+			// Executado entre a leitura e o cancelamento
+			return nil, fmt.Errorf("%w: o agendamento não está mais ativo", ErrScheduleState)
+		}
+		return nil, err
+	}
+	log.Printf("[Schedule %s] Agendamento cancelado", scheduleID)
+	m.wakeScheduler()
+	return m.DB.GetSchedule(tenantID, scheduleID)
+}
+
+func scheduleStatusLabel(status string) string {
+	switch status {
+	case models.ScheduleCompleted:
+		return "concluído"
+	case models.ScheduleFailed:
+		return "com falha"
+	case models.ScheduleCancelled:
+		return "cancelado"
+	default:
+		return "ativo"
+	}
+}
+
+// wakeScheduler faz o agendador recalcular a próxima execução
+func (m *Manager) wakeScheduler() {
+	m.schedules.mu.Lock()
+	defer m.schedules.mu.Unlock()
+
+	if m.schedules.stop == nil {
+		return
+	}
+	select {
+	case m.schedules.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Manager) scheduleLoop(wake <-chan struct{}, stop <-chan struct{}) {
+	defer m.schedules.wg.Done()
+
+	for {
+		wait := m.runDueSchedules(stop)
+		timer := time.NewTimer(wait)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// runDueSchedules executa os agendamentos vencidos e retorna a espera até a
+// próxima execução
+func (m *Manager) runDueSchedules(stop <-chan struct{}) time.Duration {
+	due, err := m.DB.ListDueSchedules(time.Now())
+	if err != nil {
+		log.Printf("[Schedule] Erro ao buscar agendamentos: %v", err)
+		return scheduleMaxWait
+	}
+	for i := range due {
+		select {
+		case <-stop:
+			return scheduleMaxWait
+		default:
+		}
+		m.runSchedule(&due[i])
+	}
+
+	next, err := m.DB.NextScheduleRun()
+	if err != nil {
+		log.Printf("[Schedule] Erro ao buscar a próxima execução: %v", err)
+		return scheduleMaxWait
+	}
+	if next == nil {
+		return scheduleMaxWait
+	}
+	return min(max(time.Until(*next), 0), scheduleMaxWait)
+}
+
+// runSchedule registra a execução, avança o agendamento e coloca a mensagem
+// na fila de envio da sessão
+func (m *Manager) runSchedule(s *models.ScheduledMessage) {
+	now := time.Now()
+	status, next := models.ScheduleCompleted, (*time.Time)(nil)
+	if s.Cron != "" {
+		status, next = m.nextScheduleRun(s, now)
+	}
+
+	claimed, err := m.DB.ClaimScheduleRun(s.ID, now, next, status)
+	if err != nil {
+		log.Printf("[Schedule %s] Erro ao registrar execução: %v", s.ID, err)
+		return
+	}
+	if !claimed {
+		return // cancelado depois da busca
+	}
+	if late := now.Sub(*s.NextRunAt); late > scheduleMaxWait {
+		log.Printf("[Schedule %s] Execução prevista para %s feita com %s de atraso", s.ID, s.NextRunAt.Format(time.RFC3339), late.Round(time.Second))
+	}
+
+	msg, err := m.enqueueSchedule(s)
+	if err != nil {
+		log.Printf("[Schedule %s] Erro ao enfileirar mensagem para %s: %v", s.ID, s.PhoneNumber, err)
+		if err := m.DB.RecordScheduleResult(s.ID, "", err.Error()); err != nil {
+			log.Printf("[Schedule %s] Erro ao gravar resultado: %v", s.ID, err)
+		}
+		if s.Cron == "" {
+			if err := m.DB.FailSchedule(s.ID); err != nil {
+				log.Printf("[Schedule %s] Erro ao marcar falha: %v", s.ID, err)
+			}
+		}
+		return
+	}
+	if err := m.DB.RecordScheduleResult(s.ID, msg.ID, ""); err != nil {
+		log.Printf("[Schedule %s] Erro ao gravar resultado: %v", s.ID, err)
+	}
+	log.Printf("[Schedule %s] Mensagem %s enfileirada para %s", s.ID, msg.ID, s.PhoneNumber)
+}
+
+// nextScheduleRun calcula a próxima execução de um agendamento recorrente a
+// partir de now, pulando as que passaram com o servidor parado
+func (m *Manager) nextScheduleRun(s *models.ScheduledMessage, now time.Time) (string, *time.Time) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		log.Printf("[Schedule %s] Fuso horário %q inválido, recorrência encerrada: %v", s.ID, s.Timezone, err)
+		return models.ScheduleCompleted, nil
+	}
+	cron, err := ParseCron(s.Cron)
+	if err != nil {
+		log.Printf("[Schedule %s] Expressão cron %q inválida, recorrência encerrada: %v", s.ID, s.Cron, err)
+		return models.ScheduleCompleted, nil
+	}
+	next := cron.Next(now.In(loc))
+	if next.IsZero() {
+		return models.ScheduleCompleted, nil
+	}
+	return models.ScheduleActive, &next
+}
+
+// enqueueSchedule monta o texto do agendamento e o coloca na fila de envio
+func (m *Manager) enqueueSchedule(s *models.ScheduledMessage) (*models.OutboundMessage, error) {
+	text := s.Body
+	if s.TemplateID != "" {
+		client, exists := m.GetClient(s.TenantID, s.SessionID)
+		if !exists {
+			return nil, storage.ErrSessionNotFound
+		}
+		rendered, err := m.RenderTemplate(s.TenantID, s.TemplateID, client.ContactVariables(s.PhoneNumber), s.Variables)
+		if err != nil {
+			return nil, err
+		}
+		text = rendered
+	}
+	return m.EnqueueMessage(s.TenantID, s.SessionID, s.PhoneNumber, text)
+}
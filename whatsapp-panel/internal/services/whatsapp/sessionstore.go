@@ -0,0 +1,336 @@
+package whatsapp
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// SessionStore resolve os arquivos de sessão do whatsmeow (um banco SQLite por
+// sessão) dentro de um único diretório. Todo acesso a esses arquivos, no
+// servidor e nas ferramentas de linha de comando, passa por aqui.
+type SessionStore struct {
+	Dir string
+	// Key, se definida, cifra os arquivos de sessão em repouso (ver storecrypt.go)
+	Key *StoreKey
+
+	mu    sync.Mutex
+	open  map[string]*memoryStore
+	locks map[string]*sessionLock // ver storelock.go
+}
+
+// NewSessionStore cria o diretório de sessões, se necessário
+func NewSessionStore(dir string) (*SessionStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório de sessões: %v", err)
+	}
+	return &SessionStore{Dir: dir}, nil
+}
+
+// Path retorna o caminho do arquivo de uma sessão
+func (s *SessionStore) Path(sessionID string) string {
+	return filepath.Join(s.Dir, sessionID+".db")
+}
+
+// Open abre (ou cria) o banco de dados whatsmeow de uma sessão, obtendo o lock
+// de dono (SessionLockedError se outro processo a tiver aberta). Com chave
+// configurada, o banco é decifrado para a memória, e arquivos ainda sem
+// criptografia são cifrados já na abertura.
+func (s *SessionStore) Open(sessionID string, logger waLog.Logger) (*sqlstore.Container, error) {
+	lock, err := s.acquire(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	container, err := s.openContainer(sessionID, logger)
+	if err != nil {
+		lock.release()
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.locks == nil {
+		s.locks = make(map[string]*sessionLock)
+	}
+	s.locks[sessionID] = lock
+	s.mu.Unlock()
+	return container, nil
+}
+
+func (s *SessionStore) openContainer(sessionID string, logger waLog.Logger) (*sqlstore.Container, error) {
+	if s.Key == nil {
+		if encrypted, _ := s.Encrypted(sessionID); encrypted {
+			return nil, ErrStoreEncrypted
+		}
+		return openStore(s.Path(sessionID), logger)
+	}
+
+	image, err := s.ReadImage(sessionID)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	mem, err := openMemoryStore(s.Path(sessionID), s.Key, image)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar store: %v", err)
+	}
+
+	container := sqlstore.NewWithDB(mem.db, "sqlite3", logger)
+	if err := container.Upgrade(); err != nil {
+		mem.close()
+		return nil, fmt.Errorf("erro ao criar store: %v", err)
+	}
+	// Gravar de imediato: cria o arquivo de sessões novas e cifra os antigos
+	if err := mem.flush(); err != nil {
+		mem.close()
+		return nil, fmt.Errorf("erro ao gravar arquivo de sessão: %v", err)
+	}
+
+	s.mu.Lock()
+	if s.open == nil {
+		s.open = make(map[string]*memoryStore)
+	}
+	s.open[sessionID] = mem
+	s.mu.Unlock()
+	return container, nil
+}
+
+// openStore abre um banco de dados whatsmeow sem criptografia em qualquer caminho
+func openStore(path string, logger waLog.Logger) (*sqlstore.Container, error) {
+	container, err := sqlstore.New("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=30000&cache=shared", path), logger)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar store: %v", err)
+	}
+	return container, nil
+}
+
+// Close fecha o banco de uma sessão aberto por Open, liberando o lock
+func (s *SessionStore) Close(sessionID string, container *sqlstore.Container) error {
+	mem, lock := s.detach(sessionID)
+	if lock != nil {
+		defer lock.release()
+	}
+	if mem != nil {
+		return mem.close()
+	}
+	return container.Close()
+}
+
+// Remove apaga o arquivo de uma sessão. Sessões abertas por outro processo
+// não são apagadas (SessionLockedError).
+func (s *SessionStore) Remove(sessionID string) error {
+	mem, lock := s.detach(sessionID)
+	if mem != nil {
+		mem.close()
+	}
+	if lock == nil {
+		var err error
+		if lock, err = s.acquire(sessionID); err != nil {
+			return err
+		}
+	}
+	defer lock.release()
+
+	err := os.Remove(s.Path(sessionID))
+	os.Remove(s.lockPath(sessionID))
+	return err
+}
+
+// detach retira a sessão das listas de bancos cifrados abertos e de locks
+func (s *SessionStore) detach(sessionID string) (*memoryStore, *sessionLock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mem, lock := s.open[sessionID], s.locks[sessionID]
+	delete(s.open, sessionID)
+	delete(s.locks, sessionID)
+	return mem, lock
+}
+
+// Encrypted informa se o arquivo da sessão está cifrado
+func (s *SessionStore) Encrypted(sessionID string) (bool, error) {
+	f, err := os.Open(s.Path(sessionID))
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(storeEncryptedMagic))
+	n, _ := f.Read(header)
+	return bytes.Equal(header[:n], storeEncryptedMagic), nil
+}
+
+// KeyID retorna o id da chave que cifrou o arquivo, ou "" se não estiver cifrado
+func (s *SessionStore) KeyID(sessionID string) (string, error) {
+	encrypted, err := s.Encrypted(sessionID)
+	if err != nil || !encrypted {
+		return "", err
+	}
+	data, err := os.ReadFile(s.Path(sessionID))
+	if err != nil {
+		return "", err
+	}
+	return storeFileKeyID(data)
+}
+
+// ReadImage retorna uma cópia consistente do banco da sessão, sem
+// criptografia: do banco em memória se ela estiver aberta, decifrando o
+// arquivo se estiver cifrado ou por VACUUM INTO, mesmo com a sessão
+// conectada e gravando no banco
+func (s *SessionStore) ReadImage(sessionID string) ([]byte, error) {
+	s.mu.Lock()
+	mem := s.open[sessionID]
+	s.mu.Unlock()
+	if mem != nil {
+		return mem.serialize()
+	}
+
+	data, err := os.ReadFile(s.Path(sessionID))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, storeEncryptedMagic) {
+		if s.Key == nil {
+			return nil, ErrStoreEncrypted
+		}
+		return s.Key.open(data)
+	}
+	if len(data) > 0 && !bytes.HasPrefix(data, sqliteMagic) {
+		return nil, fmt.Errorf("arquivo de sessão %s não é um banco SQLite", s.Path(sessionID))
+	}
+	return s.vacuumFile(sessionID)
+}
+
+// vacuumFile copia um arquivo de sessão sem criptografia por VACUUM INTO,
+// que também incorpora o journal pendente
+func (s *SessionStore) vacuumFile(sessionID string) ([]byte, error) {
+	tmp, err := os.CreateTemp(s.Dir, ".snapshot-*")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar arquivo temporário: %v", err)
+	}
+	tmp.Close()
+	// VACUUM INTO exige que o destino não exista
+	os.Remove(tmp.Name())
+	defer os.Remove(tmp.Name())
+
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro&_busy_timeout=30000", s.Path(sessionID)))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir arquivo de sessão: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`VACUUM INTO ?`, tmp.Name()); err != nil {
+		return nil, fmt.Errorf("erro ao copiar arquivo de sessão: %v", err)
+	}
+	return os.ReadFile(tmp.Name())
+}
+
+// DeviceJID retorna o JID do dispositivo gravado no banco da sessão, ou ""
+// se a sessão nunca foi pareada (ou o dispositivo foi apagado no logout)
+func (s *SessionStore) DeviceJID(sessionID string) (string, error) {
+	image, err := s.ReadImage(sessionID)
+	if err != nil || len(image) == 0 {
+		return "", err
+	}
+
+	// A imagem é lida em memória: gravá-la em disco, mesmo em um temporário,
+	// exporia as chaves de sessões cifradas
+	db := openImage(image)
+	defer db.Close()
+
+	var hasTable int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'whatsmeow_device'`).Scan(&hasTable); err != nil {
+		return "", fmt.Errorf("erro ao ler arquivo de sessão: %v", err)
+	}
+	if hasTable == 0 {
+		return "", nil
+	}
+
+	var jid string
+	err = db.QueryRow(`SELECT jid FROM whatsmeow_device LIMIT 1`).Scan(&jid)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler dispositivo: %v", err)
+	}
+	return jid, nil
+}
+
+// WriteImage grava o banco de uma sessão fechada, cifrado com a chave
+// configurada (ou sem criptografia, se não houver chave)
+func (s *SessionStore) WriteImage(sessionID string, image []byte) error {
+	lock, err := s.acquire(sessionID)
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+	return s.writeImage(sessionID, image, s.Key)
+}
+
+// Rekey regrava o arquivo de uma sessão fechada com newKey; nil remove a
+// criptografia. Arquivos já cifrados com newKey ficam como estão. Sessões
+// abertas, neste ou em outro processo, são recusadas.
+func (s *SessionStore) Rekey(sessionID string, newKey *StoreKey) error {
+	lock, err := s.acquire(sessionID)
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+
+	keyID, err := s.KeyID(sessionID)
+	if err != nil {
+		return err
+	}
+	if newKey == nil && keyID == "" || newKey != nil && keyID == newKey.ID() {
+		return nil
+	}
+
+	image, err := s.ReadImage(sessionID)
+	if err != nil {
+		return err
+	}
+	return s.writeImage(sessionID, image, newKey)
+}
+
+func (s *SessionStore) writeImage(sessionID string, image []byte, key *StoreKey) error {
+	data := image
+	if key != nil {
+		var err error
+		if data, err = key.seal(image); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(s.Path(sessionID), data)
+}
+
+// Files lista os nomes dos arquivos .db do diretório de sessões
+func (s *SessionStore) Files() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler diretório de sessões: %v", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".db" {
+			continue
+		}
+		files = append(files, entry.Name())
+	}
+	return files, nil
+}
+
+// SessionID extrai o ID da sessão do nome de um arquivo listado por Files
+func (s *SessionStore) SessionID(file string) string {
+	return strings.TrimSuffix(file, ".db")
+}
@@ -0,0 +1,144 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"whatsapp-panel/internal/models"
+)
+
+// ShutdownReport resume o que foi drenado e encerrado no desligamento
+type ShutdownReport struct {
+	SendsDrained     int64 // envios em andamento concluídos dentro do prazo
+	SendsAbandoned   int64 // envios ainda em andamento quando o prazo expirou
+	Disconnected     int   // clientes desconectados
+	PendingDiscarded int   // sessões aguardando pareamento descartadas
+	StatsFlushed     int   // sessões com estatísticas gravadas
+}
+
+func (r ShutdownReport) String() string {
+	return fmt.Sprintf("%d envios drenados, %d abandonados, %d clientes desconectados, %d sessões pendentes descartadas, %d estatísticas gravadas",
+		r.SendsDrained, r.SendsAbandoned, r.Disconnected, r.PendingDiscarded, r.StatsFlushed)
+}
+
+// errShuttingDown é retornado para envios iniciados durante o desligamento
+var errShuttingDown = fmt.Errorf("servidor em desligamento, envio recusado")
+
+// beginSend registra um envio em andamento; falha se o cliente já estiver
+// sendo encerrado
+func (c *Client) beginSend() error {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	if c.closing {
+		return errShuttingDown
+	}
+	c.sends.Add(1)
+	atomic.AddInt64(&c.inFlight, 1)
+	return nil
+}
+
+func (c *Client) endSend() {
+	atomic.AddInt64(&c.inFlight, -1)
+	c.sends.Done()
+}
+
+// closeSends passa a recusar novos envios e retorna quantos estão em andamento
+func (c *Client) closeSends() int64 {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	c.closing = true
+	return atomic.LoadInt64(&c.inFlight)
+}
+
+// Shutdown encerra todos os clientes na ordem segura para os stores sqlite:
+// recusa novos envios e aguarda os em andamento até o prazo de ctx, desconecta
+// cada cliente sem reconexão automática e grava as estatísticas finais. O banco
+// do painel deve ser fechado pelo chamador depois disso.
+func (m *Manager) Shutdown(ctx context.Context) ShutdownReport {
+	var report ShutdownReport
+
+	m.StopReconciler()
+	// O agendador para antes da fila, que recebe as mensagens dele
+	m.StopScheduler(ctx)
+	// Workers da fila e das campanhas terminam o envio em andamento; o restante fica no banco
+	m.StopOutbox(ctx)
+	m.StopCampaigns(ctx)
+
+	m.Mutex.Lock()
+	clients := make([]*Client, 0, len(m.Clients))
+	for _, client := range m.Clients {
+		clients = append(clients, client)
+	}
+	m.Mutex.Unlock()
+
+	// Drenar envios em andamento
+	var pending int64
+	for _, client := range clients {
+		pending += client.closeSends()
+	}
+	if pending > 0 {
+		log.Printf("[Shutdown] Aguardando %d envios em andamento", pending)
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		for _, client := range clients {
+			client.sends.Wait()
+		}
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		log.Printf("[Shutdown] Prazo para drenar envios expirado")
+	}
+
+	for _, client := range clients {
+		report.SendsAbandoned += atomic.LoadInt64(&client.inFlight)
+	}
+	report.SendsDrained = pending - report.SendsAbandoned
+
+	// Desconectar clientes
+	for _, client := range clients {
+		client.stopReconnect("servidor em desligamento", true)
+		client.stopWatchdog()
+		client.disconnect("servidor em desligamento")
+		// Clientes pendentes mantêm o websocket de login aberto sem estar "conectados"
+		client.WAClient.Disconnect()
+		report.Disconnected++
+
+		if client.Store != nil {
+			if err := m.Sessions.Close(client.ID, client.Store); err != nil {
+				log.Printf("[Shutdown] Erro ao fechar store da sessão %s: %v", client.ID, err)
+			}
+		}
+
+		// Sessões que nunca parearam não poderiam ser restauradas
+		if client.Status() == models.StatusPending {
+			if err := m.Sessions.Remove(client.ID); err != nil {
+				log.Printf("[Shutdown] Erro ao excluir arquivo de sessão %s: %v", client.ID, err)
+			} else {
+				report.PendingDiscarded++
+			}
+		}
+	}
+
+	// Gravar estatísticas finais. Sessões que nunca parearam não têm linha em
+	// whatsapp_sessions, à qual session_stats está ligada
+	for _, client := range clients {
+		if client.Events == nil || client.Status() == models.StatusPending {
+			continue
+		}
+		if client.Events.updateStats() == nil {
+			report.StatsFlushed++
+		}
+	}
+
+	m.Mutex.Lock()
+	m.Clients = make(map[string]*Client)
+	m.Mutex.Unlock()
+
+	return report
+}
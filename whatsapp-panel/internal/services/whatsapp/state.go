@@ -0,0 +1,83 @@
+package whatsapp
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"whatsapp-panel/internal/models"
+)
+
+// validTransitions lista, para cada status, os status que podem sucedê-lo.
+// StatusLoggedOut é terminal: um novo pareamento cria uma nova sessão.
+var validTransitions = map[string][]string{
+	models.StatusPending:      {models.StatusPaired, models.StatusConnected, models.StatusLoggedOut},
+	models.StatusPaired:       {models.StatusConnected, models.StatusDisconnected, models.StatusLoggedOut},
+	models.StatusConnected:    {models.StatusDisconnected, models.StatusLoggedOut},
+	models.StatusDisconnected: {models.StatusConnected, models.StatusLoggedOut},
+	models.StatusLoggedOut:    {},
+}
+
+// TransitionError indica uma tentativa de transição de status não permitida
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("transição de status inválida: %s -> %s", e.From, e.To)
+}
+
+// CanTransition informa se a sessão pode passar do status from para o status to
+func CanTransition(from, to string) bool {
+	for _, next := range validTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Status retorna o status atual da sessão
+func (c *Client) Status() string {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	return c.status
+}
+
+// StatusChangedAt retorna o momento da última transição de status
+func (c *Client) StatusChangedAt() time.Time {
+	c.Mutex.Lock()
+	defer c.Mutex.Unlock()
+	return c.statusChangedAt
+}
+
+// setStatus aplica uma transição de status, rejeitando as inválidas, e a
+// registra em whatsapp_sessions.status e no histórico de status
+func (c *Client) setStatus(to, reason string) error {
+	c.Mutex.Lock()
+	from := c.status
+	if from == to {
+		c.Mutex.Unlock()
+		return nil
+	}
+	if !CanTransition(from, to) {
+		c.Mutex.Unlock()
+		err := &TransitionError{From: from, To: to}
+		log.Printf("[Client %s] %v (%s)", c.ID, err, reason)
+		return err
+	}
+	c.status = to
+	c.statusChangedAt = time.Now()
+	c.Connected = to == models.StatusConnected
+	c.Mutex.Unlock()
+
+	log.Printf("[Client %s] Status mudou de %s para %s: %s", c.ID, from, to, reason)
+
+	if c.DB != nil {
+		if err := c.DB.RecordStatusTransition(c.ID, from, to, reason); err != nil {
+			log.Printf("[Client %s] Erro ao registrar transição de status: %v", c.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-panel/internal/models"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{models.StatusPending, models.StatusPaired, true},
+		{models.StatusPending, models.StatusConnected, true},
+		{models.StatusPending, models.StatusDisconnected, false},
+		{models.StatusPaired, models.StatusConnected, true},
+		{models.StatusConnected, models.StatusDisconnected, true},
+		{models.StatusConnected, models.StatusPending, false},
+		{models.StatusDisconnected, models.StatusConnected, true},
+		{models.StatusDisconnected, models.StatusPaired, false},
+		{models.StatusLoggedOut, models.StatusConnected, false},
+		{models.StatusLoggedOut, models.StatusPending, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, quer %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestClientStatusFollowsEvents(t *testing.T) {
+	m := newTestManager(t)
+	// Sem reconexão durante o teste: os eventos são emitidos à mão
+	m.Reconnect.InitialDelay = time.Hour
+
+	client, transport := pairTestClient(t, m)
+	if got := client.Status(); got != models.StatusConnected {
+		t.Fatalf("status após pareamento = %s, quer %s", got, models.StatusConnected)
+	}
+	if !client.Connected {
+		t.Error("Connected = false após pareamento")
+	}
+
+	transport.Emit(&events.Disconnected{})
+	if got := client.Status(); got != models.StatusDisconnected {
+		t.Fatalf("status após desconexão = %s, quer %s", got, models.StatusDisconnected)
+	}
+	if client.Connected {
+		t.Error("Connected = true após desconexão")
+	}
+
+	transport.Emit(&events.Connected{})
+	transport.EmitLoggedOut(events.ConnectFailureLoggedOut)
+	if got := client.Status(); got != models.StatusLoggedOut {
+		t.Fatalf("status após logout = %s, quer %s", got, models.StatusLoggedOut)
+	}
+
+	// StatusLoggedOut é terminal
+	var transitionErr *TransitionError
+	if err := client.setStatus(models.StatusConnected, "teste"); !errors.As(err, &transitionErr) {
+		t.Errorf("setStatus depois do logout = %v, quer *TransitionError", err)
+	}
+	if got := client.Status(); got != models.StatusLoggedOut {
+		t.Errorf("status = %s, quer %s", got, models.StatusLoggedOut)
+	}
+
+	history, err := m.DB.GetStatusHistory(client.ID)
+	if err != nil {
+		t.Fatalf("GetStatusHistory: %v", err)
+	}
+	var got []string
+	for i := len(history) - 1; i >= 0; i-- {
+		got = append(got, history[i].From+">"+history[i].To)
+	}
+	want := []string{
+		"pending>paired",
+		"paired>connected",
+		"connected>disconnected",
+		"disconnected>connected",
+		"connected>logged_out",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("histórico = %v, quer %v", got, want)
+	}
+
+	waitFor(t, "remoção do cliente deslogado", func() bool {
+		_, exists := m.GetClient(models.DefaultTenantID, client.ID)
+		return !exists
+	})
+}
+
+func TestShutdownFlushesStatsOfPairedSessionsOnly(t *testing.T) {
+	m := newTestManager(t)
+	m.Reconnect.InitialDelay = time.Hour
+
+	pairTestClient(t, m)
+	if _, err := m.NewClient(models.DefaultTenantID); err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	// A sessão pendente não tem linha no banco e não conta como gravada
+	report := m.Shutdown(context.Background())
+	if report.StatsFlushed != 1 {
+		t.Errorf("StatsFlushed = %d, quer 1", report.StatsFlushed)
+	}
+	if report.PendingDiscarded != 1 {
+		t.Errorf("PendingDiscarded = %d, quer 1", report.PendingDiscarded)
+	}
+}
@@ -0,0 +1,456 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Criptografia em repouso dos arquivos de sessão. Com uma chave configurada,
+// cada <id>.db guarda a imagem do banco SQLite cifrada com AES-256-GCM:
+//
+//	magic | id da chave (8 bytes) | nonce | AES-256-GCM(imagem SQLite)
+//
+// Ao abrir a sessão, a imagem é decifrada para um banco em memória usado pelo
+// sqlstore sem alterações; cada transação de escrita confirmada grava a
+// imagem cifrada de volta no arquivo, de forma atômica, antes de retornar.
+
+// storeEncryptedMagic identifica um arquivo de sessão cifrado
+var storeEncryptedMagic = []byte("WAPANEL-STORE-ENC1\n")
+
+// sqliteMagic é o cabeçalho de um arquivo SQLite sem criptografia
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+const storeKeyIDSize = 8
+
+var (
+	// ErrStoreEncrypted indica um arquivo de sessão cifrado aberto sem chave
+	ErrStoreEncrypted = errors.New("arquivo de sessão cifrado; configure SESSION_KEY ou SESSION_KEY_FILE")
+	// ErrStoreWrongKey indica um arquivo de sessão cifrado com outra chave
+	ErrStoreWrongKey = errors.New("arquivo de sessão cifrado com outra chave")
+)
+
+// StoreKey é a chave AES-256 dos arquivos de sessão
+type StoreKey struct {
+	key []byte
+	id  []byte
+}
+
+// ParseStoreKey lê uma chave de 32 bytes em hexadecimal (64 caracteres) ou base64
+func ParseStoreKey(value string) (*StoreKey, error) {
+	value = strings.TrimSpace(value)
+	key, err := hex.DecodeString(value)
+	if err != nil || len(key) != 32 {
+		key, err = base64.StdEncoding.DecodeString(value)
+	}
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("chave de sessão inválida: use 32 bytes em hexadecimal ou base64")
+	}
+	sum := sha256.Sum256(key)
+	return &StoreKey{key: key, id: sum[:storeKeyIDSize]}, nil
+}
+
+// LoadStoreKey carrega a chave informada diretamente ou, se vazia, do
+// arquivo. Retorna nil (sem criptografia) se ambos estiverem vazios.
+func LoadStoreKey(value, file string) (*StoreKey, error) {
+	if value == "" && file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler arquivo da chave de sessão: %v", err)
+		}
+		value = string(data)
+	}
+	if value == "" {
+		return nil, nil
+	}
+	return ParseStoreKey(value)
+}
+
+// GenerateStoreKey cria uma chave aleatória, retornada em hexadecimal
+func GenerateStoreKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("erro ao gerar chave: %v", err)
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// ID identifica a chave sem revelá-la (prefixo do SHA-256)
+func (k *StoreKey) ID() string {
+	return hex.EncodeToString(k.id)
+}
+
+// seal cifra a imagem de um banco SQLite
+func (k *StoreKey) seal(image []byte) ([]byte, error) {
+	gcm, err := k.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("erro ao gerar nonce: %v", err)
+	}
+
+	header := append(append([]byte{}, storeEncryptedMagic...), k.id...)
+	out := append(append([]byte{}, header...), nonce...)
+	return gcm.Seal(out, nonce, image, header), nil
+}
+
+// open decifra um arquivo gerado por seal
+func (k *StoreKey) open(data []byte) ([]byte, error) {
+	keyID, err := storeFileKeyID(data)
+	if err != nil {
+		return nil, err
+	}
+	if keyID != k.ID() {
+		return nil, fmt.Errorf("%w (chave %s, configurada %s)", ErrStoreWrongKey, keyID, k.ID())
+	}
+
+	gcm, err := k.aead()
+	if err != nil {
+		return nil, err
+	}
+	headerSize := len(storeEncryptedMagic) + storeKeyIDSize
+	if len(data) < headerSize+gcm.NonceSize() {
+		return nil, fmt.Errorf("arquivo de sessão cifrado truncado")
+	}
+	nonce := data[headerSize : headerSize+gcm.NonceSize()]
+	image, err := gcm.Open(nil, nonce, data[headerSize+gcm.NonceSize():], data[:headerSize])
+	if err != nil {
+		return nil, fmt.Errorf("arquivo de sessão corrompido ou adulterado")
+	}
+	return image, nil
+}
+
+func (k *StoreKey) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// storeFileKeyID retorna o id da chave de um arquivo cifrado
+func storeFileKeyID(data []byte) (string, error) {
+	if !bytes.HasPrefix(data, storeEncryptedMagic) || len(data) < len(storeEncryptedMagic)+storeKeyIDSize {
+		return "", fmt.Errorf("arquivo de sessão não está cifrado")
+	}
+	return hex.EncodeToString(data[len(storeEncryptedMagic) : len(storeEncryptedMagic)+storeKeyIDSize]), nil
+}
+
+// memoryStore é um banco de sessão decifrado em memória. Toda transação de
+// escrita confirmada é gravada cifrada no arquivo antes de retornar ao
+// sqlstore, então uma queda do processo não perde chaves do Signal.
+type memoryStore struct {
+	db   *sql.DB
+	path string
+	key  *StoreKey
+
+	mu    sync.Mutex
+	image []byte // última imagem gravada, usada se a conexão for recriada
+}
+
+// memoryConnector cria a conexão do banco em memória a partir da última
+// imagem gravada
+type memoryConnector struct {
+	store *memoryStore
+}
+
+func (c *memoryConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.store.mu.Lock()
+	image := c.store.image
+	c.store.mu.Unlock()
+
+	conn, err := loadMemoryConn(image)
+	if err != nil {
+		return nil, err
+	}
+	mc := &memoryConn{SQLiteConn: conn.(*sqlite3.SQLiteConn), store: c.store}
+	mc.RegisterCommitHook(func() int {
+		mc.dirty = true
+		return 0
+	})
+	return mc, nil
+}
+
+func (c *memoryConnector) Driver() driver.Driver {
+	return &sqlite3.SQLiteDriver{}
+}
+
+// memoryConn é a conexão do banco em memória. Após cada comando fora de
+// transação e após cada COMMIT, grava o banco se alguma escrita foi
+// confirmada (ver RegisterCommitHook).
+type memoryConn struct {
+	*sqlite3.SQLiteConn
+	store *memoryStore
+	dirty bool
+}
+
+// persist grava a imagem atual se houve escrita confirmada. Dentro de uma
+// transação não faz nada: a gravação acontece no COMMIT.
+func (c *memoryConn) persist() error {
+	if !c.dirty || !c.AutoCommit() {
+		return nil
+	}
+	image, err := c.Serialize("main")
+	if err != nil {
+		return fmt.Errorf("erro ao gravar arquivo de sessão: %v", err)
+	}
+	if err := c.store.write(image); err != nil {
+		return fmt.Errorf("erro ao gravar arquivo de sessão: %v", err)
+	}
+	c.dirty = false
+	return nil
+}
+
+func (c *memoryConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.SQLiteConn.ExecContext(ctx, query, args)
+	if persistErr := c.persist(); err == nil {
+		err = persistErr
+	}
+	return result, err
+}
+
+func (c *memoryConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	// O sqlstore grava apenas com Exec; escritas feitas por consultas são
+	// gravadas na próxima operação da conexão
+	if err := c.persist(); err != nil {
+		return nil, err
+	}
+	return c.SQLiteConn.QueryContext(ctx, query, args)
+}
+
+func (c *memoryConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.persist(); err != nil {
+		return nil, err
+	}
+	stmt, err := c.SQLiteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryStmt{SQLiteStmt: stmt.(*sqlite3.SQLiteStmt), conn: c}, nil
+}
+
+func (c *memoryConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *memoryConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.persist(); err != nil {
+		return nil, err
+	}
+	tx, err := c.SQLiteConn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryTx{Tx: tx, conn: c}, nil
+}
+
+func (c *memoryConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// IsValid impede que o database/sql descarte a conexão, o que recarregaria
+// o banco a partir do arquivo
+func (c *memoryConn) IsValid() bool {
+	return true
+}
+
+// memoryTx grava o banco após o COMMIT
+type memoryTx struct {
+	driver.Tx
+	conn *memoryConn
+}
+
+func (tx *memoryTx) Commit() error {
+	if err := tx.Tx.Commit(); err != nil {
+		return err
+	}
+	return tx.conn.persist()
+}
+
+// memoryStmt grava o banco após comandos preparados fora de transação
+type memoryStmt struct {
+	*sqlite3.SQLiteStmt
+	conn *memoryConn
+}
+
+func (s *memoryStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	result, err := s.SQLiteStmt.ExecContext(ctx, args)
+	if persistErr := s.conn.persist(); err == nil {
+		err = persistErr
+	}
+	return result, err
+}
+
+func (s *memoryStmt) Exec(args []driver.Value) (driver.Result, error) {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return s.ExecContext(context.Background(), named)
+}
+
+// loadMemoryConn abre um banco em memória com o conteúdo da imagem. O
+// Deserialize do SQLite gera um banco de tamanho fixo, então a imagem é
+// carregada em uma conexão auxiliar e copiada pela API de backup.
+func loadMemoryConn(image []byte) (driver.Conn, error) {
+	sqliteDriver := &sqlite3.SQLiteDriver{}
+	conn, err := sqliteDriver.Open("file::memory:?_foreign_keys=on&_busy_timeout=30000")
+	if err != nil {
+		return nil, err
+	}
+	if len(image) == 0 {
+		return conn, nil
+	}
+
+	src, err := sqliteDriver.Open("file::memory:")
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer src.Close()
+
+	if err := src.(*sqlite3.SQLiteConn).Deserialize(image, "main"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("erro ao carregar banco de sessão: %v", err)
+	}
+	backup, err := conn.(*sqlite3.SQLiteConn).Backup("main", src.(*sqlite3.SQLiteConn), "main")
+	if err == nil {
+		_, err = backup.Step(-1)
+		if finishErr := backup.Finish(); err == nil {
+			err = finishErr
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("erro ao carregar banco de sessão: %v", err)
+	}
+	return conn, nil
+}
+
+// imageConnector abre uma imagem de banco somente em memória, para leituras
+// que não podem deixar a imagem sem criptografia no disco
+type imageConnector struct {
+	image []byte
+}
+
+func (c *imageConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return loadMemoryConn(c.image)
+}
+
+func (c *imageConnector) Driver() driver.Driver {
+	return &sqlite3.SQLiteDriver{}
+}
+
+// openImage abre uma cópia em memória da imagem de um banco de sessão
+func openImage(image []byte) *sql.DB {
+	db := sql.OpenDB(&imageConnector{image: image})
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+// openMemoryStore carrega a imagem em uma conexão em memória única, fixa
+// durante toda a vida do store
+func openMemoryStore(path string, key *StoreKey, image []byte) (*memoryStore, error) {
+	store := &memoryStore{path: path, key: key, image: image}
+	store.db = sql.OpenDB(&memoryConnector{store: store})
+	// Uma única conexão, nunca reciclada: o banco em memória pertence a ela
+	store.db.SetMaxOpenConns(1)
+	store.db.SetMaxIdleConns(1)
+	store.db.SetConnMaxLifetime(0)
+	store.db.SetConnMaxIdleTime(0)
+	if err := store.db.Ping(); err != nil {
+		store.db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// flush grava a imagem atual, mesmo sem escritas pendentes. Usado na
+// abertura, para criar o arquivo de sessões novas e cifrar os antigos.
+func (s *memoryStore) flush() error {
+	image, err := s.serialize()
+	if err != nil {
+		return err
+	}
+	return s.write(image)
+}
+
+// write cifra a imagem e a grava no arquivo
+func (s *memoryStore) write(image []byte) error {
+	sealed, err := s.key.seal(image)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, sealed); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.image = image
+	s.mu.Unlock()
+	return nil
+}
+
+// serialize retorna a imagem atual do banco
+func (s *memoryStore) serialize() ([]byte, error) {
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var image []byte
+	err = conn.Raw(func(driverConn interface{}) error {
+		var err error
+		image, err = driverConn.(*memoryConn).Serialize("main")
+		return err
+	})
+	return image, err
+}
+
+// close fecha o banco; as escritas já foram gravadas a cada COMMIT
+func (s *memoryStore) close() error {
+	return s.db.Close()
+}
+
+// writeFileAtomic grava o arquivo por meio de um temporário renomeado, para
+// que uma queda no meio da gravação não corrompa a sessão
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,136 @@
+package whatsapp
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.mau.fi/whatsmeow/types"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+func testStoreKey(t *testing.T) *StoreKey {
+	t.Helper()
+	value, err := GenerateStoreKey()
+	if err != nil {
+		t.Fatalf("GenerateStoreKey: %v", err)
+	}
+	key, err := ParseStoreKey(value)
+	if err != nil {
+		t.Fatalf("ParseStoreKey: %v", err)
+	}
+	return key
+}
+
+// pairStoredDevice abre a sessão no store, grava um dispositivo pareado e
+// fecha a sessão, retornando o JID gravado
+func pairStoredDevice(t *testing.T, s *SessionStore, sessionID string) types.JID {
+	t.Helper()
+	container, err := s.Open(sessionID, waLog.Noop)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	jid := types.NewADJID("5511999990000", 0, 1)
+	NewFakeTransport(container.NewDevice()).EmitPairSuccess(jid)
+
+	// A escrita confirmada já está no arquivo, cifrada, antes do Close
+	data, err := os.ReadFile(s.Path(sessionID))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if s.Key != nil {
+		if !bytes.HasPrefix(data, storeEncryptedMagic) || bytes.Contains(data, sqliteMagic) || bytes.Contains(data, []byte(jid.User)) {
+			t.Fatal("arquivo da sessão aberta não está cifrado")
+		}
+		image, err := s.Key.open(data)
+		if err != nil {
+			t.Fatalf("decifrar arquivo da sessão aberta: %v", err)
+		}
+		if !bytes.Contains(image, []byte(jid.User)) {
+			t.Fatal("dispositivo pareado não foi gravado no arquivo antes do Close")
+		}
+	}
+
+	if err := s.Close(sessionID, container); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return jid
+}
+
+func TestSessionStoreEncryptedRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	s := &SessionStore{Dir: dir, Key: testStoreKey(t)}
+	sessionID := uuid.New().String()
+	jid := pairStoredDevice(t, s, sessionID)
+
+	if keyID, err := s.KeyID(sessionID); err != nil || keyID != s.Key.ID() {
+		t.Errorf("KeyID = %q, %v, quer %q", keyID, err, s.Key.ID())
+	}
+	if got, err := s.DeviceJID(sessionID); err != nil || got != jid.String() {
+		t.Errorf("DeviceJID = %q, %v, quer %q", got, err, jid)
+	}
+
+	container, err := s.Open(sessionID, waLog.Noop)
+	if err != nil {
+		t.Fatalf("reabrir: %v", err)
+	}
+	device, err := container.GetFirstDevice()
+	if err != nil || device.ID == nil || *device.ID != jid {
+		t.Errorf("dispositivo reaberto = %v, %v, quer %s", device.ID, err, jid)
+	}
+	s.Close(sessionID, container)
+
+	if _, err := (&SessionStore{Dir: dir}).Open(sessionID, waLog.Noop); !errors.Is(err, ErrStoreEncrypted) {
+		t.Errorf("abrir sem chave = %v, quer ErrStoreEncrypted", err)
+	}
+	if _, err := (&SessionStore{Dir: dir, Key: testStoreKey(t)}).Open(sessionID, waLog.Noop); !errors.Is(err, ErrStoreWrongKey) {
+		t.Errorf("abrir com outra chave = %v, quer ErrStoreWrongKey", err)
+	}
+}
+
+func TestSessionStoreRekey(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, newKey := testStoreKey(t), testStoreKey(t)
+	s := &SessionStore{Dir: dir, Key: oldKey}
+	sessionID := uuid.New().String()
+	jid := pairStoredDevice(t, s, sessionID)
+
+	// Sessões abertas não são regravadas
+	container, err := s.Open(sessionID, waLog.Noop)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	var lockedErr *SessionLockedError
+	if err := s.Rekey(sessionID, newKey); !errors.As(err, &lockedErr) {
+		t.Errorf("Rekey com a sessão aberta = %v, quer *SessionLockedError", err)
+	}
+	s.Close(sessionID, container)
+
+	if err := s.Rekey(sessionID, newKey); err != nil {
+		t.Fatalf("Rekey: %v", err)
+	}
+	if keyID, _ := s.KeyID(sessionID); keyID != newKey.ID() {
+		t.Errorf("KeyID após Rekey = %q, quer %q", keyID, newKey.ID())
+	}
+	if _, err := s.DeviceJID(sessionID); !errors.Is(err, ErrStoreWrongKey) {
+		t.Errorf("ler com a chave antiga = %v, quer ErrStoreWrongKey", err)
+	}
+	rekeyed := &SessionStore{Dir: dir, Key: newKey}
+	if got, err := rekeyed.DeviceJID(sessionID); err != nil || got != jid.String() {
+		t.Errorf("DeviceJID com a chave nova = %q, %v, quer %q", got, err, jid)
+	}
+
+	// nil remove a criptografia
+	if err := rekeyed.Rekey(sessionID, nil); err != nil {
+		t.Fatalf("Rekey(nil): %v", err)
+	}
+	data, err := os.ReadFile(s.Path(sessionID))
+	if err != nil || !bytes.HasPrefix(data, sqliteMagic) {
+		t.Fatalf("arquivo após Rekey(nil) não é um banco SQLite: %v", err)
+	}
+	if got, err := (&SessionStore{Dir: dir}).DeviceJID(sessionID); err != nil || got != jid.String() {
+		t.Errorf("DeviceJID sem criptografia = %q, %v, quer %q", got, err, jid)
+	}
+}
@@ -0,0 +1,145 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cada sessão aberta é protegida por um lock exclusivo em <id>.lock, ao lado
+// do arquivo da sessão. O lock pertence ao processo que mantém o Client: outro
+// servidor ou ferramenta apontando para o mesmo diretório recusa abrir, apagar
+// ou regravar a sessão, em vez de conectar o mesmo dispositivo duas vezes. O
+// sistema operacional libera o lock se o processo morrer.
+
+// errLockBusy indica que o lock do arquivo pertence a outro descritor
+var errLockBusy = errors.New("lock em uso")
+
+// StoreOwner identifica o processo dono de uma sessão
+type StoreOwner struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Since   time.Time `json:"since"`
+	// Self indica que o dono é este processo
+	Self bool `json:"self"`
+}
+
+func (o *StoreOwner) String() string {
+	return fmt.Sprintf("%s (pid %d em %s) desde %s", o.Command, o.PID, o.Host, o.Since.Format("02/01/2006 15:04:05"))
+}
+
+// SessionLockedError indica uma sessão em uso por outro processo
+type SessionLockedError struct {
+	SessionID string
+	Owner     *StoreOwner // nil se o dono não puder ser identificado
+}
+
+func (e *SessionLockedError) Error() string {
+	if e.Owner == nil {
+		return fmt.Sprintf("sessão %s em uso por outro processo", e.SessionID)
+	}
+	return fmt.Sprintf("sessão %s em uso por %s", e.SessionID, e.Owner)
+}
+
+// sessionLock é o lock de uma sessão mantido por este processo
+type sessionLock struct {
+	f     *os.File
+	owner StoreOwner
+}
+
+func (s *SessionStore) lockPath(sessionID string) string {
+	return filepath.Join(s.Dir, sessionID+".lock")
+}
+
+// acquire obtém o lock da sessão sem esperar, gravando no arquivo quem é o dono
+func (s *SessionStore) acquire(sessionID string) (*sessionLock, error) {
+	f, err := os.OpenFile(s.lockPath(sessionID), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir lock da sessão: %v", err)
+	}
+	if err := tryLockFile(f); err != nil {
+		f.Close()
+		if err == errLockBusy {
+			owner, _ := readLockOwner(s.lockPath(sessionID))
+			return nil, &SessionLockedError{SessionID: sessionID, Owner: owner}
+		}
+		return nil, fmt.Errorf("erro ao obter lock da sessão: %v", err)
+	}
+
+	lock := &sessionLock{f: f, owner: currentOwner()}
+	data, err := json.Marshal(lock.owner)
+	if err == nil {
+		err = f.Truncate(0)
+	}
+	if err == nil {
+		_, err = f.WriteAt(data, 0)
+	}
+	if err != nil {
+		lock.release()
+		return nil, fmt.Errorf("erro ao gravar lock da sessão: %v", err)
+	}
+	return lock, nil
+}
+
+func (l *sessionLock) release() {
+	unlockFile(l.f)
+	l.f.Close()
+}
+
+// Owner retorna o processo dono da sessão, ou nil se ela não estiver aberta
+// em nenhum processo
+func (s *SessionStore) Owner(sessionID string) (*StoreOwner, error) {
+	s.mu.Lock()
+	lock := s.locks[sessionID]
+	s.mu.Unlock()
+	if lock != nil {
+		owner := lock.owner
+		owner.Self = true
+		return &owner, nil
+	}
+
+	f, err := os.Open(s.lockPath(sessionID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Se o lock estiver livre, o arquivo é apenas resto de um dono anterior
+	if err := tryLockFile(f); err == nil {
+		unlockFile(f)
+		return nil, nil
+	} else if err != errLockBusy {
+		return nil, err
+	}
+	return readLockOwner(s.lockPath(sessionID))
+}
+
+func readLockOwner(path string) (*StoreOwner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var owner StoreOwner
+	if err := json.Unmarshal(data, &owner); err != nil {
+		return nil, fmt.Errorf("lock sem identificação do dono")
+	}
+	return &owner, nil
+}
+
+// currentOwner identifica este processo
+func currentOwner() StoreOwner {
+	host, _ := os.Hostname()
+	return StoreOwner{
+		PID:     os.Getpid(),
+		Host:    host,
+		Command: filepath.Base(os.Args[0]),
+		Since:   time.Now(),
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package whatsapp
+
+import "os"
+
+// Sem flock nesta plataforma: o arquivo de lock registra o dono, mas não
+// impede outro processo de abrir a sessão
+func tryLockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
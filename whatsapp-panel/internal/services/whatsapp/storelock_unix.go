@@ -0,0 +1,21 @@
+//go:build unix
+
+package whatsapp
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLockFile obtém um flock exclusivo sem esperar
+func tryLockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return errLockBusy
+	}
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
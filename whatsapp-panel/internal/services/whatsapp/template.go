@@ -0,0 +1,167 @@
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"go.mau.fi/whatsmeow/types"
+
+	"whatsapp-panel/internal/models"
+)
+
+var (
+	// ErrMissingVariable indica uma variável da mensagem sem valor
+	ErrMissingVariable = errors.New("variável sem valor")
+	// ErrInvalidTemplate indica um modelo de mensagem inválido
+	ErrInvalidTemplate = errors.New("modelo inválido")
+)
+
+// templateNameMaxLength limita o nome de um modelo de mensagem
+const templateNameMaxLength = 100
+
+// placeholderPattern reconhece variáveis no formato {{nome}}, com espaços opcionais
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([\p{L}\p{N}_]+)\s*\}\}`)
+
+// MissingVariablesError lista as variáveis sem valor de uma mensagem
+type MissingVariablesError struct {
+	Names []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrMissingVariable, strings.Join(e.Names, ", "))
+}
+
+func (e *MissingVariablesError) Is(target error) bool {
+	return target == ErrMissingVariable
+}
+
+// MessageVariables retorna as variáveis usadas no texto, em minúsculas, na
+// ordem em que aparecem e sem repetição
+func MessageVariables(text string) []string {
+	names := []string{}
+	seen := make(map[string]bool)
+	for _, match := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(match[1])
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// RenderMessage substitui as variáveis do texto pelos valores de vars, cujas
+// chaves devem estar em minúsculas. Variáveis ausentes ou vazias resultam em
+// *MissingVariablesError.
+func RenderMessage(text string, vars map[string]string) (string, error) {
+	var missing []string
+	for _, name := range MessageVariables(text) {
+		if strings.TrimSpace(vars[name]) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", &MissingVariablesError{Names: missing}
+	}
+
+	return placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		return strings.TrimSpace(vars[strings.ToLower(name)])
+	}), nil
+}
+
+// MergeVariables combina conjuntos de variáveis com as chaves em minúsculas;
+// valores não vazios dos conjuntos seguintes têm precedência
+func MergeVariables(sets ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, vars := range sets {
+		for name, value := range vars {
+			if strings.TrimSpace(value) != "" {
+				merged[strings.ToLower(strings.TrimSpace(name))] = value
+			}
+		}
+	}
+	return merged
+}
+
+// ValidateTemplate normaliza e verifica o nome e o texto de um modelo
+func ValidateTemplate(t *models.MessageTemplate) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return fmt.Errorf("%w: informe o nome", ErrInvalidTemplate)
+	}
+	if utf8.RuneCountInString(t.Name) > templateNameMaxLength {
+		return fmt.Errorf("%w: nome acima de %d caracteres", ErrInvalidTemplate, templateNameMaxLength)
+	}
+	if strings.TrimSpace(t.Body) == "" {
+		return fmt.Errorf("%w: informe o texto", ErrInvalidTemplate)
+	}
+	t.Variables = MessageVariables(t.Body)
+	return nil
+}
+
+// ContactVariables retorna as variáveis de um número a partir dos contatos da
+// sessão: {{numero}} e, se o contato for conhecido, {{nome}}
+func (c *Client) ContactVariables(phoneNumber string) map[string]string {
+	vars := map[string]string{campaignVarNumber: phoneNumber}
+	jid, err := recipientJID(phoneNumber)
+	if err != nil || c.WAClient == nil {
+		return vars
+	}
+	if name := c.contactName(jid); name != "" {
+		vars[campaignVarName] = name
+	}
+	return vars
+}
+
+// contactName retorna o nome de um contato da sessão, ou vazio se ele não for
+// conhecido
+func (c *Client) contactName(jid types.JID) string {
+	info, err := c.WAClient.GetContact(jid)
+	if err != nil {
+		log.Printf("[Client %s] Erro ao ler contato %s: %v", c.ID, jid.User, err)
+		return ""
+	}
+	return displayName(info)
+}
+
+// contactNames retorna o nome de todos os contatos conhecidos da sessão, em
+// uma única leitura do store
+func (c *Client) contactNames() map[types.JID]string {
+	contacts, err := c.WAClient.GetAllContacts()
+	if err != nil {
+		log.Printf("[Client %s] Erro ao ler contatos: %v", c.ID, err)
+		return nil
+	}
+	names := make(map[types.JID]string, len(contacts))
+	for jid, info := range contacts {
+		if name := displayName(info); name != "" {
+			names[jid] = name
+		}
+	}
+	return names
+}
+
+// displayName escolhe o nome de exibição de um contato
+func displayName(info types.ContactInfo) string {
+	for _, name := range []string{info.FullName, info.PushName, info.BusinessName, info.FirstName} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// RenderTemplate renderiza um modelo do tenant com as variáveis informadas,
+// em ordem crescente de precedência
+func (m *Manager) RenderTemplate(tenantID, templateID string, vars ...map[string]string) (string, error) {
+	t, err := m.DB.GetTemplate(tenantID, templateID)
+	if err != nil {
+		return "", err
+	}
+	return RenderMessage(t.Body, MergeVariables(vars...))
+}
@@ -0,0 +1,47 @@
+package whatsapp
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRenderMessage(t *testing.T) {
+	text, err := RenderMessage("Olá {{ Nome }}, seu pedido {{pedido}} saiu. {{nome}}!", map[string]string{
+		"nome":   " Ana ",
+		"pedido": "1234",
+	})
+	if err != nil {
+		t.Fatalf("RenderMessage: %v", err)
+	}
+	if want := "Olá Ana, seu pedido 1234 saiu. Ana!"; text != want {
+		t.Errorf("RenderMessage = %q, quer %q", text, want)
+	}
+}
+
+func TestRenderMessageMissingVariables(t *testing.T) {
+	text, err := RenderMessage("{{nome}}, pedido {{pedido}} em {{cidade}}", map[string]string{
+		"nome":   "Ana",
+		"pedido": "  ",
+	})
+	if !errors.Is(err, ErrMissingVariable) {
+		t.Fatalf("erro = %v, quer ErrMissingVariable", err)
+	}
+	var missing *MissingVariablesError
+	if !errors.As(err, &missing) {
+		t.Fatalf("erro = %T, quer *MissingVariablesError", err)
+	}
+	if want := []string{"pedido", "cidade"}; !reflect.DeepEqual(missing.Names, want) {
+		t.Errorf("variáveis ausentes = %v, quer %v", missing.Names, want)
+	}
+	if text != "" {
+		t.Errorf("texto = %q, quer vazio em caso de erro", text)
+	}
+}
+
+func TestRenderMessageWithoutVariables(t *testing.T) {
+	text, err := RenderMessage("Mensagem fixa", nil)
+	if err != nil || text != "Mensagem fixa" {
+		t.Errorf("RenderMessage = %q, %v", text, err)
+	}
+}
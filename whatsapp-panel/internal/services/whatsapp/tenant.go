@@ -0,0 +1,60 @@
+package whatsapp
+
+import (
+	"fmt"
+
+	"whatsapp-panel/internal/models"
+)
+
+// QuotaExceededError indica que o tenant já atingiu o limite de sessões
+type QuotaExceededError struct {
+	TenantID    string
+	MaxSessions int
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("limite de %d sessões do workspace %s atingido", e.MaxSessions, e.TenantID)
+}
+
+// GetClient retorna o cliente da sessão somente se ele pertencer ao tenant
+func (m *Manager) GetClient(tenantID, sessionID string) (*Client, bool) {
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+
+	client, exists := m.Clients[sessionID]
+	if !exists || client.TenantID != tenantID {
+		return nil, false
+	}
+	return client, true
+}
+
+// CheckQuota retorna um *QuotaExceededError se o tenant não puder criar mais
+// uma sessão. Contam as sessões gravadas que não foram deslogadas e as que
+// ainda aguardam pareamento (sem linha no banco).
+func (m *Manager) CheckQuota(tenantID string) error {
+	tenant, err := m.DB.GetTenant(tenantID)
+	if err != nil {
+		return fmt.Errorf("erro ao carregar workspace: %v", err)
+	}
+	if tenant.MaxSessions <= 0 {
+		return nil
+	}
+
+	count, err := m.DB.CountActiveSessions(tenantID)
+	if err != nil {
+		return fmt.Errorf("erro ao contar sessões do workspace: %v", err)
+	}
+
+	m.Mutex.Lock()
+	for _, client := range m.Clients {
+		if client.TenantID == tenantID && client.Status() == models.StatusPending {
+			count++
+		}
+	}
+	m.Mutex.Unlock()
+
+	if !tenant.HasQuota(count) {
+		return &QuotaExceededError{TenantID: tenantID, MaxSessions: tenant.MaxSessions}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package whatsapp
+
+import (
+	"context"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/types"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// Transport define as operações do whatsmeow usadas pelo painel. Permite trocar
+// a conexão real por uma implementação em memória (FakeTransport).
+type Transport interface {
+	Connect() error
+	Disconnect()
+	IsConnected() bool
+	IsLoggedIn() bool
+	GetQRChannel(ctx context.Context) (<-chan whatsmeow.QRChannelItem, error)
+	PairPhone(phone string, showPushNotification bool, clientType whatsmeow.PairClientType, clientDisplayName string) (string, error)
+	SendMessage(ctx context.Context, to types.JID, message *waProto.Message, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error)
+	// Upload cifra e envia uma mídia aos servidores do WhatsApp, retornando as
+	// chaves e o caminho usados na mensagem
+	Upload(ctx context.Context, plaintext []byte, appInfo whatsmeow.MediaType) (whatsmeow.UploadResponse, error)
+	AddEventHandler(handler whatsmeow.EventHandler) uint32
+	GetAllContacts() (map[types.JID]types.ContactInfo, error)
+	GetContact(jid types.JID) (types.ContactInfo, error)
+	Logout() error
+	// SetProxyAddress define o proxy (http, https ou socks5) usado nas próximas
+	// conexões; "" remove o proxy
+	SetProxyAddress(addr string, opts ...whatsmeow.SetProxyOptions) error
+	// DeviceID retorna o JID do dispositivo pareado, ou nil se ainda não pareado
+	DeviceID() *types.JID
+}
+
+// TransportFactory cria o Transport de um dispositivo
+type TransportFactory func(device *store.Device, logger waLog.Logger) Transport
+
+// whatsmeowTransport implementa Transport sobre um *whatsmeow.Client real
+type whatsmeowTransport struct {
+	*whatsmeow.Client
+}
+
+// NewWhatsmeowTransport cria um Transport conectado à rede do WhatsApp
+func NewWhatsmeowTransport(device *store.Device, logger waLog.Logger) Transport {
+	client := whatsmeow.NewClient(device, logger)
+	// A reconexão é supervisionada pelo Client (ver reconnect.go)
+	client.EnableAutoReconnect = false
+	return &whatsmeowTransport{Client: client}
+}
+
+func (t *whatsmeowTransport) GetAllContacts() (map[types.JID]types.ContactInfo, error) {
+	return t.Store.Contacts.GetAllContacts()
+}
+
+func (t *whatsmeowTransport) GetContact(jid types.JID) (types.ContactInfo, error) {
+	return t.Store.Contacts.GetContact(jid)
+}
+
+func (t *whatsmeowTransport) DeviceID() *types.JID {
+	return t.Store.ID
+}
+
+// Garantir que as implementações satisfazem Transport
+var (
+	_ Transport = (*whatsmeowTransport)(nil)
+	_ Transport = (*FakeTransport)(nil)
+)
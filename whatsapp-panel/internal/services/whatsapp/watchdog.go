@@ -0,0 +1,235 @@
+package whatsapp
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"whatsapp-panel/internal/models"
+)
+
+// WatchdogPolicy configura a verificação periódica de saúde de cada sessão
+type WatchdogPolicy struct {
+	Interval         time.Duration // intervalo entre verificações (0 desativa o watchdog)
+	InboundTimeout   time.Duration // tempo sem eventos recebidos até a sessão ser considerada degradada
+	KeepAliveMaxFail time.Duration // tempo sem keepalive respondido até a sessão ser considerada zumbi
+}
+
+// DefaultWatchdogPolicy retorna a política usada quando nada é configurado
+func DefaultWatchdogPolicy() WatchdogPolicy {
+	return WatchdogPolicy{
+		Interval:         30 * time.Second,
+		InboundTimeout:   30 * time.Minute,
+		KeepAliveMaxFail: whatsmeow.KeepAliveMaxFailTime,
+	}
+}
+
+// Estados de saúde, derivados da pontuação
+const (
+	HealthHealthy   = "healthy"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+	HealthOffline   = "offline"
+)
+
+// unhealthyScore é a pontuação abaixo da qual o watchdog força a reconexão
+const unhealthyScore = 50
+
+// Health resume a saúde de uma sessão: pontuação de 0 a 100 e o motivo
+type Health struct {
+	Score             int        `json:"score"`
+	State             string     `json:"state"`
+	Reason            string     `json:"reason"`
+	CheckedAt         time.Time  `json:"checked_at"`
+	LastEventAt       *time.Time `json:"last_event_at,omitempty"`
+	KeepAliveFailures int        `json:"keepalive_failures"`
+	ForcedReconnects  int        `json:"forced_reconnects"`
+	LastForcedAt      *time.Time `json:"last_forced_reconnect_at,omitempty"`
+}
+
+// healthState guarda os sinais observados pelo watchdog de um Client
+type healthState struct {
+	mu     sync.Mutex
+	policy WatchdogPolicy
+	stop   chan struct{}
+
+	lastEventAt       time.Time
+	keepAliveFailures int
+	keepAliveLastOK   time.Time
+	forcedReconnects  int
+	lastForcedAt      time.Time
+}
+
+// observeEvent registra os eventos do whatsmeow relevantes para a saúde da sessão
+func (c *Client) observeEvent(evt interface{}) {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+
+	switch e := evt.(type) {
+	case *events.KeepAliveTimeout:
+		// Gerado localmente, não conta como evento recebido
+		c.health.keepAliveFailures = e.ErrorCount
+		c.health.keepAliveLastOK = e.LastSuccess
+		return
+	case *events.KeepAliveRestored, *events.Connected:
+		c.health.keepAliveFailures = 0
+	}
+	c.health.lastEventAt = time.Now()
+}
+
+// Health calcula a saúde atual da sessão
+func (c *Client) Health() Health {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	return c.checkHealth(time.Now())
+}
+
+// checkHealth pontua a sessão a partir do status, do estado do socket, dos
+// keepalives e do tempo desde o último evento recebido. Deve ser chamado com
+// c.health.mu travado.
+func (c *Client) checkHealth(now time.Time) Health {
+	h := Health{
+		CheckedAt:         now,
+		KeepAliveFailures: c.health.keepAliveFailures,
+		ForcedReconnects:  c.health.forcedReconnects,
+	}
+	if !c.health.lastEventAt.IsZero() {
+		lastEvent := c.health.lastEventAt
+		h.LastEventAt = &lastEvent
+	}
+	if !c.health.lastForcedAt.IsZero() {
+		lastForced := c.health.lastForcedAt
+		h.LastForcedAt = &lastForced
+	}
+
+	switch status := c.Status(); status {
+	case models.StatusConnected:
+	case models.StatusPending:
+		h.State, h.Reason = HealthOffline, "aguardando pareamento"
+		return h
+	case models.StatusLoggedOut:
+		h.State, h.Reason = HealthOffline, "sessão deslogada"
+		return h
+	default:
+		h.State, h.Reason = HealthOffline, "sessão não conectada"
+		if info := c.ReconnectInfo(); info.Active {
+			h.Reason = fmt.Sprintf("reconectando (tentativa %d)", info.Attempt)
+		}
+		return h
+	}
+
+	policy := c.health.policy
+	score := 100
+	var reasons []string
+
+	if !c.WAClient.IsConnected() {
+		score = 0
+		reasons = append(reasons, "socket fechado, mas a sessão consta como conectada")
+	} else if !c.WAClient.IsLoggedIn() {
+		score -= 60
+		reasons = append(reasons, "socket aberto sem autenticação")
+	}
+
+	if c.health.keepAliveFailures > 0 {
+		failingFor := now.Sub(c.health.keepAliveLastOK).Round(time.Second)
+		if policy.KeepAliveMaxFail > 0 && failingFor > policy.KeepAliveMaxFail {
+			score -= 60
+			reasons = append(reasons, fmt.Sprintf("keepalive sem resposta há %s", failingFor))
+		} else {
+			score -= 20
+			reasons = append(reasons, fmt.Sprintf("%d keepalive(s) sem resposta", c.health.keepAliveFailures))
+		}
+	}
+
+	if policy.InboundTimeout > 0 && !c.health.lastEventAt.IsZero() {
+		if idle := now.Sub(c.health.lastEventAt); idle > policy.InboundTimeout {
+			score -= 30
+			reasons = append(reasons, fmt.Sprintf("nenhum evento recebido há %s", idle.Round(time.Second)))
+		}
+	}
+
+	if score < 0 {
+		score = 0
+	}
+	h.Score = score
+	switch {
+	case score >= 80:
+		h.State = HealthHealthy
+	case score >= unhealthyScore:
+		h.State = HealthDegraded
+	default:
+		h.State = HealthUnhealthy
+	}
+	h.Reason = "ok"
+	if len(reasons) > 0 {
+		h.Reason = strings.Join(reasons, "; ")
+	}
+	return h
+}
+
+// startWatchdog inicia a verificação periódica da sessão
+func (c *Client) startWatchdog(policy WatchdogPolicy) {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+
+	c.health.policy = policy
+	if policy.Interval <= 0 || c.health.stop != nil {
+		return
+	}
+	c.health.stop = make(chan struct{})
+	go c.watchdogLoop(policy.Interval, c.health.stop)
+}
+
+// stopWatchdog encerra a verificação periódica da sessão
+func (c *Client) stopWatchdog() {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+
+	if c.health.stop != nil {
+		close(c.health.stop)
+		c.health.stop = nil
+	}
+}
+
+func (c *Client) watchdogLoop(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.watchdogCheck()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// watchdogCheck força a reconexão de uma sessão conectada cuja saúde caiu
+// abaixo de unhealthyScore (socket zumbi, keepalive sem resposta, etc.)
+func (c *Client) watchdogCheck() {
+	c.health.mu.Lock()
+	h := c.checkHealth(time.Now())
+	if h.State != HealthUnhealthy {
+		c.health.mu.Unlock()
+		return
+	}
+	c.health.forcedReconnects++
+	c.health.lastForcedAt = h.CheckedAt
+	c.health.keepAliveFailures = 0
+	c.health.mu.Unlock()
+
+	reason := "watchdog: " + h.Reason
+	log.Printf("[Watchdog %s] Sessão não saudável (pontuação %d), forçando reconexão: %s", c.ID, h.Score, h.Reason)
+
+	// O whatsmeow não emite Disconnected em desconexões explícitas, então o
+	// status e a reconexão são tratados aqui
+	c.WAClient.Disconnect()
+	c.setStatus(models.StatusDisconnected, reason)
+	c.startReconnect(reason)
+}
@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"whatsapp-panel/internal/models"
+)
+
+// ErrCampaignNotFound indica que a campanha não existe no tenant
+var ErrCampaignNotFound = errors.New("campanha não encontrada")
+
+// campaignSelect lê a campanha com a contagem de destinatários por estado
+const campaignSelect = `
+	SELECT c.id, c.tenant_id, c.session_id, c.name, c.message, c.template_id, c.status, c.created_at, c.updated_at, c.finished_at,
+	       COUNT(r.id),
+	       COALESCE(SUM(r.status = 'pending'), 0),
+	       COALESCE(SUM(r.status = 'sent'), 0),
+	       COALESCE(SUM(r.status = 'failed'), 0),
+	       COALESCE(SUM(r.status = 'skipped'), 0)
+	FROM campaigns c
+	LEFT JOIN campaign_recipients r ON r.campaign_id = c.id`
+
+const recipientColumns = `id, campaign_id, phone_number, name, fields, status, error, wa_message_id, sent_at`
+
+// CreateCampaign grava a campanha e a lista de destinatários numa transação.
+// Uma campanha criada já concluída (sem nada a enviar) recebe finished_at.
+func (d *Database) CreateCampaign(c *models.Campaign, recipients []models.CampaignRecipient) error {
+	now := time.Now().UTC()
+	c.CreatedAt, c.UpdatedAt = now, now
+	var finishedAt interface{}
+	if c.Status == models.CampaignCompleted {
+		c.FinishedAt = &now
+		finishedAt = now
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO campaigns (id, tenant_id, session_id, name, message, template_id, status, created_at, updated_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, c.ID, c.TenantID, c.SessionID, c.Name, c.Message, c.TemplateID, c.Status, now, now, finishedAt)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO campaign_recipients (campaign_id, phone_number, name, fields, status, error)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	c.Counts = models.CampaignCounts{}
+	for _, r := range recipients {
+		fields, err := encodeStringMap(r.Fields)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(c.ID, r.PhoneNumber, r.Name, fields, r.Status, r.Error); err != nil {
+			return err
+		}
+		c.Counts.Add(r.Status)
+	}
+	return tx.Commit()
+}
+
+// GetCampaign retorna a campanha do tenant, ou ErrCampaignNotFound
+func (d *Database) GetCampaign(tenantID, id string) (*models.Campaign, error) {
+	row := d.db.QueryRow(campaignSelect+` WHERE c.tenant_id = ? AND c.id = ? GROUP BY c.id`, tenantID, id)
+	c, err := scanCampaign(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrCampaignNotFound
+	}
+	return c, err
+}
+
+// ListCampaigns retorna as campanhas do tenant, da mais recente para a mais
+// antiga, opcionalmente só as de uma sessão
+func (d *Database) ListCampaigns(tenantID, sessionID string) ([]models.Campaign, error) {
+	query := campaignSelect + ` WHERE c.tenant_id = ?`
+	args := []interface{}{tenantID}
+	if sessionID != "" {
+		query += ` AND c.session_id = ?`
+		args = append(args, sessionID)
+	}
+	query += ` GROUP BY c.id ORDER BY c.created_at DESC`
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	campaigns := []models.Campaign{}
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, *c)
+	}
+	return campaigns, rows.Err()
+}
+
+// ListRunningCampaigns retorna as campanhas em andamento, de todos os tenants
+func (d *Database) ListRunningCampaigns() ([]models.Campaign, error) {
+	rows, err := d.db.Query(campaignSelect+` WHERE c.status = ? GROUP BY c.id ORDER BY c.created_at`, models.CampaignRunning)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var campaigns []models.Campaign
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, *c)
+	}
+	return campaigns, rows.Err()
+}
+
+// SetCampaignStatus altera o estado da campanha; os estados finais registram finished_at
+func (d *Database) SetCampaignStatus(id, status string) error {
+	now := time.Now().UTC()
+	var finishedAt interface{}
+	if status == models.CampaignCompleted || status == models.CampaignCancelled {
+		finishedAt = now
+	}
+	result, err := d.db.Exec(`UPDATE campaigns SET status = ?, updated_at = ?, finished_at = ? WHERE id = ?`,
+		status, now, finishedAt, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrCampaignNotFound
+	}
+	return nil
+}
+
+// NextCampaignRecipient retorna o próximo destinatário pendente da campanha,
+// na ordem da lista, ou nil se não houver
+func (d *Database) NextCampaignRecipient(campaignID string) (*models.CampaignRecipient, error) {
+	row := d.db.QueryRow(`
+		SELECT `+recipientColumns+`
+		FROM campaign_recipients
+		WHERE campaign_id = ? AND status = ?
+		ORDER BY id
+		LIMIT 1
+	`, campaignID, models.RecipientPending)
+	r, err := scanRecipient(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// MarkRecipientSent registra o envio aceito pelo WhatsApp
+func (d *Database) MarkRecipientSent(id int64, waMessageID string, sentAt time.Time) error {
+	_, err := d.db.Exec(`UPDATE campaign_recipients SET status = ?, error = '', wa_message_id = ?, sent_at = ? WHERE id = ?`,
+		models.RecipientSent, waMessageID, sentAt.UTC(), id)
+	return err
+}
+
+// MarkRecipient encerra um destinatário sem envio (falha ou ignorado), com o motivo
+func (d *Database) MarkRecipient(id int64, status, reason string) error {
+	_, err := d.db.Exec(`UPDATE campaign_recipients SET status = ?, error = ? WHERE id = ?`, status, reason, id)
+	return err
+}
+
+// SkipPendingRecipients marca como ignorados os destinatários ainda pendentes da campanha
+func (d *Database) SkipPendingRecipients(campaignID, reason string) (int64, error) {
+	result, err := d.db.Exec(`UPDATE campaign_recipients SET status = ?, error = ? WHERE campaign_id = ? AND status = ?`,
+		models.RecipientSkipped, reason, campaignID, models.RecipientPending)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListCampaignRecipients retorna os destinatários da campanha na ordem da
+// lista, opcionalmente filtrados por estado
+func (d *Database) ListCampaignRecipients(campaignID, status string, limit, offset int) ([]models.CampaignRecipient, error) {
+	query := `SELECT ` + recipientColumns + ` FROM campaign_recipients WHERE campaign_id = ?`
+	args := []interface{}{campaignID}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY id LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := []models.CampaignRecipient{}
+	for rows.Next() {
+		r, err := scanRecipient(rows)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, *r)
+	}
+	return recipients, rows.Err()
+}
+
+// deleteSessionCampaigns remove as campanhas da sessão e seus destinatários
+func deleteSessionCampaigns(tx *sql.Tx, sessionID string) error {
+	_, err := tx.Exec(`DELETE FROM campaign_recipients WHERE campaign_id IN (SELECT id FROM campaigns WHERE session_id = ?)`, sessionID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM campaigns WHERE session_id = ?`, sessionID)
+	return err
+}
+
+func scanCampaign(row rowScanner) (*models.Campaign, error) {
+	var (
+		c          models.Campaign
+		finishedAt sql.NullTime
+	)
+	err := row.Scan(&c.ID, &c.TenantID, &c.SessionID, &c.Name, &c.Message, &c.TemplateID, &c.Status, &c.CreatedAt, &c.UpdatedAt, &finishedAt,
+		&c.Counts.Total, &c.Counts.Pending, &c.Counts.Sent, &c.Counts.Failed, &c.Counts.Skipped)
+	if err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		c.FinishedAt = &finishedAt.Time
+	}
+	return &c, nil
+}
+
+func scanRecipient(row rowScanner) (*models.CampaignRecipient, error) {
+	var (
+		r      models.CampaignRecipient
+		fields string
+		sentAt sql.NullTime
+	)
+	err := row.Scan(&r.ID, &r.CampaignID, &r.PhoneNumber, &r.Name, &fields, &r.Status, &r.Error, &r.WAMessageID, &sentAt)
+	if err != nil {
+		return nil, err
+	}
+	if fields != "" {
+		if err := json.Unmarshal([]byte(fields), &r.Fields); err != nil {
+			return nil, err
+		}
+	}
+	if sentAt.Valid {
+		r.SentAt = &sentAt.Time
+	}
+	return &r, nil
+}
+
+// encodeStringMap grava um mapa de texto (colunas do contato, variáveis) como
+// JSON, ou vazio se não houver valores
+func encodeStringMap(fields map[string]string) (string, error) {
+	if len(fields) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -1,12 +1,86 @@
 package storage
 
+import (
+	"time"
+
+	"whatsapp-panel/internal/models"
+)
+
 // Database define a interface para operações no banco de dados
 type DatabaseInterface interface {
 	Close() error
-	SaveSession(id, name, jid, phoneNumber string) error
-	UpdateSessionStats(sessionID string, contacts, groups, conversations int) error
-	GetAllSessions() ([]map[string]interface{}, error)
-	DeleteSession(id string) error
+	SaveSession(id, tenantID, name, jid, phoneNumber, status string) error
+	LinkSession(id, jid, phoneNumber, status string) error
+	UpdateSessionStats(sessionID string, contacts, groups, conversations int, messageCount int64) error
+	GetSessionStats(sessionID string) (models.Stats, error)
+	MarkSessionConnected(id string) error
+	GetAllSessions(tenantID string, tags ...string) ([]map[string]interface{}, error)
+	GetAllTags(tenantID string) ([]string, error)
+	UpdateSessionMetadata(tenantID, id string, name, notes *string, tags []string) error
+	DeleteSession(tenantID, id string) error
+	GetSession(tenantID, id string) (*models.Session, error)
+	InsertSession(tenantID string, s *models.Session) error
+	GetSessionTenant(id string) (string, error)
+	SetSessionProxy(tenantID, id, proxyURL string) error
+	GetSessionProxy(id string) (string, error)
+	SetSessionRateLimits(tenantID, id string, limits *models.RateLimits) error
+	GetSessionRateLimits(id string) (*models.RateLimits, error)
+	ListSessionRefs() (map[string]models.SessionRef, error)
+	CountActiveSessions(tenantID string) (int, error)
+	RecordStatusTransition(sessionID, from, to, reason string) error
+	GetStatusHistory(sessionID string) ([]models.StatusTransition, error)
+	CreateTenant(id, name string, maxSessions int) (*models.Tenant, error)
+	RotateTenantKey(id string) (string, error)
+	SetTenantQuota(id string, maxSessions int) error
+	GetTenant(id string) (*models.Tenant, error)
+	GetTenantByAPIKey(apiKey string) (*models.Tenant, error)
+	ListTenants() ([]models.Tenant, error)
+	CountTenantKeys() (int, error)
+	RecordReconcileAction(a *models.ReconcileAction) error
+	GetReconcileAudit(limit int) ([]models.ReconcileAction, error)
+	EnqueueOutbound(m *models.OutboundMessage) error
+	NextOutboundMessage(sessionID string) (*models.OutboundMessage, error)
+	MarkOutboundSending(id string) error
+	MarkOutboundSent(id string, attempts int, waMessageID string, sentAt time.Time) error
+	RetryOutbound(id string, attempts int, lastError string, next time.Time) error
+	DeadOutbound(id string, attempts int, lastError string) error
+	ReleaseOutbound(id string) error
+	ResetSendingOutbound() (int64, error)
+	DeadOutboundForSession(sessionID, reason string) (int64, error)
+	ListOutboundSessions() ([]string, error)
+	ListOutbound(tenantID, sessionID, status string, limit, offset int) ([]models.OutboundMessage, error)
+	GetOutbound(tenantID, id string) (*models.OutboundMessage, error)
+	RequeueDeadOutbound(tenantID, id string) error
+	CreateCampaign(c *models.Campaign, recipients []models.CampaignRecipient) error
+	GetCampaign(tenantID, id string) (*models.Campaign, error)
+	ListCampaigns(tenantID, sessionID string) ([]models.Campaign, error)
+	ListRunningCampaigns() ([]models.Campaign, error)
+	SetCampaignStatus(id, status string) error
+	NextCampaignRecipient(campaignID string) (*models.CampaignRecipient, error)
+	MarkRecipientSent(id int64, waMessageID string, sentAt time.Time) error
+	MarkRecipient(id int64, status, reason string) error
+	SkipPendingRecipients(campaignID, reason string) (int64, error)
+	ListCampaignRecipients(campaignID, status string, limit, offset int) ([]models.CampaignRecipient, error)
+	CreateTemplate(t *models.MessageTemplate) error
+	UpdateTemplate(t *models.MessageTemplate) error
+	GetTemplate(tenantID, id string) (*models.MessageTemplate, error)
+	ListTemplates(tenantID string) ([]models.MessageTemplate, error)
+	DeleteTemplate(tenantID, id string) error
+	CreateSchedule(s *models.ScheduledMessage) error
+	GetSchedule(tenantID, id string) (*models.ScheduledMessage, error)
+	ListSchedules(tenantID, sessionID, status string) ([]models.ScheduledMessage, error)
+	ListDueSchedules(now time.Time) ([]models.ScheduledMessage, error)
+	NextScheduleRun() (*time.Time, error)
+	ClaimScheduleRun(id string, runAt time.Time, next *time.Time, status string) (bool, error)
+	RecordScheduleResult(id, messageID, lastError string) error
+	FailSchedule(id string) error
+	CancelSchedule(tenantID, id string) error
+	RecordMessage(m *models.Message) error
+	GetMessage(tenantID, id string) (*models.Message, error)
+	MarkMessagesReceipt(sessionID string, waMessageIDs []string, status string, at time.Time) (int64, error)
+	ListSentSince(sessionID string, since time.Time) ([]time.Time, error)
+	ListChats(tenantID, sessionID string, limit, offset int) ([]models.Chat, error)
+	ListChatMessages(tenantID, sessionID, chatJID string, limit, offset int) ([]models.Message, error)
 }
 
 // Garantir que Database implementa DatabaseInterface
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"whatsapp-panel/internal/models"
+)
+
+// ErrMessageNotFound indica que a mensagem não existe no tenant
+var ErrMessageNotFound = errors.New("mensagem não encontrada")
+
+const messageColumns = `id, session_id, tenant_id, wa_message_id, direction, chat_jid, sender_jid, sender_name, phone_number,
+	type, body, media_mimetype, media_filename, media_size, media_direct_path, quoted_message_id, status, error,
+	timestamp, sent_at, delivered_at, read_at, played_at, created_at, updated_at`
+
+// messageProgress são os estados posteriores ao envio, na ordem em que os
+// recibos chegam, com a coluna do horário de cada um
+var messageProgress = []struct {
+	status string
+	column string
+}{
+	{models.MessageDelivered, "delivered_at"},
+	{models.MessageRead, "read_at"},
+	{models.MessagePlayed, "played_at"},
+}
+
+// RecordMessage grava uma mensagem enviada ou recebida. Uma mensagem que já
+// existe na sessão (mesmo ID do WhatsApp na mesma conversa) é ignorada.
+func (d *Database) RecordMessage(m *models.Message) error {
+	now := time.Now().UTC()
+	m.CreatedAt, m.UpdatedAt = now, now
+	if m.Timestamp.IsZero() {
+		m.Timestamp = now
+	}
+	media := m.Media
+	if media == nil {
+		media = &models.MessageMedia{}
+	}
+	_, err := d.db.Exec(`
+		INSERT OR IGNORE INTO messages (id, session_id, tenant_id, wa_message_id, direction, chat_jid, sender_jid, sender_name,
+			phone_number, type, body, media_mimetype, media_filename, media_size, media_direct_path, quoted_message_id,
+			status, error, timestamp, sent_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, m.ID, m.SessionID, m.TenantID, m.WAMessageID, m.Direction, m.ChatJID, m.SenderJID, m.SenderName,
+		m.PhoneNumber, m.Type, m.Body, media.MimeType, media.FileName, media.Size, media.DirectPath, m.QuotedMessageID,
+		m.Status, m.Error, m.Timestamp.UTC(), utcOrNil(m.SentAt), now, now)
+	return err
+}
+
+// GetMessage retorna uma mensagem do tenant pelo ID do painel ou pelo ID do
+// WhatsApp, ou ErrMessageNotFound
+func (d *Database) GetMessage(tenantID, id string) (*models.Message, error) {
+	row := d.db.QueryRow(`
+		SELECT `+messageColumns+` FROM messages
+		WHERE tenant_id = ? AND (id = ? OR wa_message_id = ?)
+		ORDER BY id = ? DESC
+		LIMIT 1
+	`, tenantID, id, id, id)
+	m, err := scanMessage(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrMessageNotFound
+	}
+	return m, err
+}
+
+// MarkMessagesReceipt aplica um recibo às mensagens da sessão com os IDs do
+// WhatsApp informados. O estado nunca retrocede (um recibo de entrega depois
+// do de leitura é ignorado), e os horários das etapas anteriores que não
+// tiveram recibo próprio recebem o horário deste. Retorna quantas mensagens
+// foram atualizadas.
+func (d *Database) MarkMessagesReceipt(sessionID string, waMessageIDs []string, status string, at time.Time) (int64, error) {
+	if len(waMessageIDs) == 0 {
+		return 0, nil
+	}
+	stage := -1
+	for i, p := range messageProgress {
+		if p.status == status {
+			stage = i
+		}
+	}
+	if stage < 0 {
+		return 0, fmt.Errorf("estado de recibo inválido: %s", status)
+	}
+
+	var sets []string
+	var args []interface{}
+	for _, p := range messageProgress[:stage+1] {
+		sets = append(sets, p.column+" = COALESCE("+p.column+", ?)")
+		args = append(args, at.UTC())
+	}
+	// Estados anteriores a este, que avançam
+	before := []interface{}{models.MessageSent}
+	for _, p := range messageProgress[:stage] {
+		before = append(before, p.status)
+	}
+	sets = append(sets, "status = CASE WHEN status IN ("+placeholders(len(before))+") THEN ? ELSE status END", "updated_at = ?")
+	args = append(args, before...)
+	args = append(args, status, time.Now().UTC(), sessionID, models.MessageOutbound)
+	for _, id := range waMessageIDs {
+		args = append(args, id)
+	}
+
+	result, err := d.db.Exec(`
+		UPDATE messages SET `+strings.Join(sets, ", ")+`
+		WHERE session_id = ? AND direction = ? AND wa_message_id IN (`+placeholders(len(waMessageIDs))+`)
+	`, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListSentSince retorna os horários das mensagens enviadas pela sessão a
+// partir de since, em ordem, por qualquer caminho de envio
+func (d *Database) ListSentSince(sessionID string, since time.Time) ([]time.Time, error) {
+	rows, err := d.db.Query(`
+		SELECT sent_at FROM messages
+		WHERE session_id = ? AND direction = ? AND sent_at >= ?
+		ORDER BY sent_at
+	`, sessionID, models.MessageOutbound, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sent []time.Time
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		sent = append(sent, t)
+	}
+	return sent, rows.Err()
+}
+
+// ListChats retorna as conversas da sessão, da mais recente para a mais
+// antiga, cada uma com a última mensagem e o total de mensagens gravadas
+func (d *Database) ListChats(tenantID, sessionID string, limit, offset int) ([]models.Chat, error) {
+	rows, err := d.db.Query(`
+		SELECT `+messageColumns+`, total FROM (
+			SELECT *,
+				COUNT(*) OVER (PARTITION BY chat_jid) AS total,
+				ROW_NUMBER() OVER (PARTITION BY chat_jid ORDER BY timestamp DESC, created_at DESC) AS position
+			FROM messages
+			WHERE tenant_id = ? AND session_id = ? AND chat_jid != ''
+		)
+		WHERE position = 1
+		ORDER BY timestamp DESC, created_at DESC
+		LIMIT ? OFFSET ?
+	`, tenantID, sessionID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chats := []models.Chat{}
+	for rows.Next() {
+		var chat models.Chat
+		m, err := scanMessage(rows, &chat.MessageCount)
+		if err != nil {
+			return nil, err
+		}
+		chat.JID, chat.LastMessage = m.ChatJID, *m
+		chats = append(chats, chat)
+	}
+	return chats, rows.Err()
+}
+
+// ListChatMessages retorna as mensagens de uma conversa da sessão, da mais
+// recente para a mais antiga
+func (d *Database) ListChatMessages(tenantID, sessionID, chatJID string, limit, offset int) ([]models.Message, error) {
+	rows, err := d.db.Query(`
+		SELECT `+messageColumns+` FROM messages
+		WHERE tenant_id = ? AND session_id = ? AND chat_jid = ?
+		ORDER BY timestamp DESC, created_at DESC
+		LIMIT ? OFFSET ?
+	`, tenantID, sessionID, chatJID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, *m)
+	}
+	return messages, rows.Err()
+}
+
+// placeholders retorna n marcadores "?" separados por vírgula
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// scanMessage lê as colunas de messageColumns, seguidas de extra
+func scanMessage(row rowScanner, extra ...interface{}) (*models.Message, error) {
+	var (
+		m                                     models.Message
+		media                                 models.MessageMedia
+		timestamp                             sql.NullTime
+		sentAt, deliveredAt, readAt, playedAt sql.NullTime
+	)
+	dest := []interface{}{&m.ID, &m.SessionID, &m.TenantID, &m.WAMessageID, &m.Direction, &m.ChatJID, &m.SenderJID,
+		&m.SenderName, &m.PhoneNumber, &m.Type, &m.Body, &media.MimeType, &media.FileName, &media.Size, &media.DirectPath,
+		&m.QuotedMessageID, &m.Status, &m.Error, &timestamp, &sentAt, &deliveredAt, &readAt, &playedAt, &m.CreatedAt, &m.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if media != (models.MessageMedia{}) {
+		m.Media = &media
+	}
+	m.Timestamp = timestamp.Time
+	if !timestamp.Valid {
+		m.Timestamp = m.CreatedAt
+	}
+	m.SentAt = timeOrNil(sentAt)
+	m.DeliveredAt = timeOrNil(deliveredAt)
+	m.ReadAt = timeOrNil(readAt)
+	m.PlayedAt = timeOrNil(playedAt)
+	return &m, nil
+}
@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"whatsapp-panel/internal/models"
+)
+
+// ErrOutboundNotFound indica que a mensagem não existe na fila do tenant
+var ErrOutboundNotFound = errors.New("mensagem não encontrada")
+
+// Os horários da fila são gravados em UTC para que a ordenação por
+// next_attempt_at não dependa do fuso do servidor
+
+const outboundColumns = `id, session_id, tenant_id, phone_number, body, status, attempts, last_error,
+	wa_message_id, next_attempt_at, created_at, updated_at, sent_at`
+
+// EnqueueOutbound grava uma mensagem na fila de envio
+func (d *Database) EnqueueOutbound(m *models.OutboundMessage) error {
+	now := time.Now().UTC()
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = now
+	}
+	if m.NextAttemptAt.IsZero() {
+		m.NextAttemptAt = now
+	}
+	m.UpdatedAt = now
+	if m.Status == "" {
+		m.Status = models.OutboundQueued
+	}
+	_, err := d.db.Exec(
+		`INSERT INTO outbound_messages (id, session_id, tenant_id, phone_number, body, status, next_attempt_at, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.ID, m.SessionID, m.TenantID, m.PhoneNumber, m.Body, m.Status, m.NextAttemptAt.UTC(), m.CreatedAt.UTC(), m.UpdatedAt,
+	)
+	return err
+}
+
+// NextOutboundMessage retorna a próxima mensagem na fila da sessão (a de
+// próxima tentativa mais cedo, que pode estar no futuro), ou nil se não houver
+func (d *Database) NextOutboundMessage(sessionID string) (*models.OutboundMessage, error) {
+	row := d.db.QueryRow(`
+		SELECT `+outboundColumns+`
+		FROM outbound_messages
+		WHERE session_id = ? AND status = ?
+		ORDER BY next_attempt_at, created_at
+		LIMIT 1
+	`, sessionID, models.OutboundQueued)
+	m, err := scanOutbound(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return m, err
+}
+
+// MarkOutboundSending marca a mensagem como em envio por um worker
+func (d *Database) MarkOutboundSending(id string) error {
+	return d.updateOutbound(`UPDATE outbound_messages SET status = ?, updated_at = ? WHERE id = ?`,
+		models.OutboundSending, time.Now().UTC(), id)
+}
+
+// MarkOutboundSent registra o envio aceito pelo WhatsApp
+func (d *Database) MarkOutboundSent(id string, attempts int, waMessageID string, sentAt time.Time) error {
+	return d.updateOutbound(`
+		UPDATE outbound_messages
+		SET status = ?, attempts = ?, last_error = '', wa_message_id = ?, sent_at = ?, updated_at = ?
+		WHERE id = ?
+	`, models.OutboundSent, attempts, waMessageID, sentAt.UTC(), time.Now().UTC(), id)
+}
+
+// RetryOutbound devolve a mensagem à fila após uma falha, para nova tentativa em next
+func (d *Database) RetryOutbound(id string, attempts int, lastError string, next time.Time) error {
+	return d.updateOutbound(`
+		UPDATE outbound_messages
+		SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ?, updated_at = ?
+		WHERE id = ?
+	`, models.OutboundQueued, attempts, lastError, next.UTC(), time.Now().UTC(), id)
+}
+
+// DeadOutbound move a mensagem para o estado final de falha (dead-letter)
+func (d *Database) DeadOutbound(id string, attempts int, lastError string) error {
+	return d.updateOutbound(`
+		UPDATE outbound_messages SET status = ?, attempts = ?, last_error = ?, updated_at = ? WHERE id = ?
+	`, models.OutboundDead, attempts, lastError, time.Now().UTC(), id)
+}
+
+// ReleaseOutbound devolve à fila uma mensagem em envio que não chegou a ser
+// tentada, sem contar tentativa
+func (d *Database) ReleaseOutbound(id string) error {
+	return d.updateOutbound(`UPDATE outbound_messages SET status = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		models.OutboundQueued, time.Now().UTC(), id, models.OutboundSending)
+}
+
+func (d *Database) updateOutbound(query string, args ...interface{}) error {
+	_, err := d.db.Exec(query, args...)
+	return err
+}
+
+// ResetSendingOutbound devolve à fila as mensagens que estavam em envio
+// quando o processo parou. O envio pode ter chegado ao WhatsApp, então essas
+// mensagens podem ser entregues em duplicidade.
+func (d *Database) ResetSendingOutbound() (int64, error) {
+	result, err := d.db.Exec(`UPDATE outbound_messages SET status = ?, updated_at = ? WHERE status = ?`,
+		models.OutboundQueued, time.Now().UTC(), models.OutboundSending)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeadOutboundForSession move para dead-letter todas as mensagens na fila da sessão
+func (d *Database) DeadOutboundForSession(sessionID, reason string) (int64, error) {
+	result, err := d.db.Exec(`
+		UPDATE outbound_messages SET status = ?, last_error = ?, updated_at = ?
+		WHERE session_id = ? AND status IN (?, ?)
+	`, models.OutboundDead, reason, time.Now().UTC(), sessionID, models.OutboundQueued, models.OutboundSending)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListOutboundSessions retorna as sessões com mensagens na fila
+func (d *Database) ListOutboundSessions() ([]string, error) {
+	rows, err := d.db.Query(`SELECT DISTINCT session_id FROM outbound_messages WHERE status = ?`, models.OutboundQueued)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, id)
+	}
+	return sessions, rows.Err()
+}
+
+// ListOutbound retorna as mensagens da fila da sessão, da mais recente para a
+// mais antiga, opcionalmente filtradas por estado
+func (d *Database) ListOutbound(tenantID, sessionID, status string, limit, offset int) ([]models.OutboundMessage, error) {
+	query := `SELECT ` + outboundColumns + ` FROM outbound_messages WHERE tenant_id = ? AND session_id = ?`
+	args := []interface{}{tenantID, sessionID}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at DESC LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.OutboundMessage{}
+	for rows.Next() {
+		m, err := scanOutbound(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, *m)
+	}
+	return messages, rows.Err()
+}
+
+// GetOutbound retorna uma mensagem da fila do tenant, ou ErrOutboundNotFound
+func (d *Database) GetOutbound(tenantID, id string) (*models.OutboundMessage, error) {
+	row := d.db.QueryRow(`SELECT `+outboundColumns+` FROM outbound_messages WHERE tenant_id = ? AND id = ?`, tenantID, id)
+	m, err := scanOutbound(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrOutboundNotFound
+	}
+	return m, err
+}
+
+// RequeueDeadOutbound devolve à fila uma mensagem em dead-letter, zerando as
+// tentativas. Retorna ErrOutboundNotFound se ela não existir ou não estiver em dead-letter.
+func (d *Database) RequeueDeadOutbound(tenantID, id string) error {
+	now := time.Now().UTC()
+	result, err := d.db.Exec(`
+		UPDATE outbound_messages
+		SET status = ?, attempts = 0, next_attempt_at = ?, updated_at = ?
+		WHERE tenant_id = ? AND id = ? AND status = ?
+	`, models.OutboundQueued, now, now, tenantID, id, models.OutboundDead)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrOutboundNotFound
+	}
+	return nil
+}
+
+// rowScanner é satisfeito por *sql.Row e *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOutbound(row rowScanner) (*models.OutboundMessage, error) {
+	var (
+		m      models.OutboundMessage
+		sentAt sql.NullTime
+	)
+	err := row.Scan(&m.ID, &m.SessionID, &m.TenantID, &m.PhoneNumber, &m.Body, &m.Status, &m.Attempts, &m.LastError,
+		&m.WAMessageID, &m.NextAttemptAt, &m.CreatedAt, &m.UpdatedAt, &sentAt)
+	if err != nil {
+		return nil, err
+	}
+	if sentAt.Valid {
+		m.SentAt = &sentAt.Time
+	}
+	return &m, nil
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"whatsapp-panel/internal/models"
+)
+
+// ErrScheduleNotFound indica que o agendamento não existe no tenant
+var ErrScheduleNotFound = errors.New("agendamento não encontrado")
+
+// Como na fila de envio, os horários são gravados em UTC; o fuso do
+// agendamento só é usado para calcular as execuções
+
+const scheduleColumns = `id, session_id, tenant_id, phone_number, body, template_id, variables, send_at, cron, timezone,
+	status, next_run_at, last_run_at, last_message_id, last_error, run_count, created_at, updated_at`
+
+// CreateSchedule grava um agendamento
+func (d *Database) CreateSchedule(s *models.ScheduledMessage) error {
+	variables, err := encodeStringMap(s.Variables)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	s.CreatedAt, s.UpdatedAt = now, now
+	_, err = d.db.Exec(`
+		INSERT INTO scheduled_messages (id, session_id, tenant_id, phone_number, body, template_id, variables, send_at, cron,
+			timezone, status, next_run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.ID, s.SessionID, s.TenantID, s.PhoneNumber, s.Body, s.TemplateID, variables, utcOrNil(s.SendAt), s.Cron,
+		s.Timezone, s.Status, utcOrNil(s.NextRunAt), now, now)
+	return err
+}
+
+// GetSchedule retorna um agendamento do tenant, ou ErrScheduleNotFound
+func (d *Database) GetSchedule(tenantID, id string) (*models.ScheduledMessage, error) {
+	row := d.db.QueryRow(`SELECT `+scheduleColumns+` FROM scheduled_messages WHERE tenant_id = ? AND id = ?`, tenantID, id)
+	s, err := scanSchedule(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrScheduleNotFound
+	}
+	return s, err
+}
+
+// ListSchedules retorna os agendamentos da sessão, do mais recente para o
+// mais antigo, opcionalmente filtrados por estado
+func (d *Database) ListSchedules(tenantID, sessionID, status string) ([]models.ScheduledMessage, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM scheduled_messages WHERE tenant_id = ? AND session_id = ?`
+	args := []interface{}{tenantID, sessionID}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at DESC`
+	return d.querySchedules(query, args...)
+}
+
+// ListDueSchedules retorna os agendamentos ativos com execução até now
+func (d *Database) ListDueSchedules(now time.Time) ([]models.ScheduledMessage, error) {
+	return d.querySchedules(`
+		SELECT `+scheduleColumns+` FROM scheduled_messages
+		WHERE status = ? AND next_run_at <= ?
+		ORDER BY next_run_at
+	`, models.ScheduleActive, now.UTC())
+}
+
+// NextScheduleRun retorna a próxima execução entre os agendamentos ativos,
+// ou nil se não houver nenhum
+func (d *Database) NextScheduleRun() (*time.Time, error) {
+	var next sql.NullTime
+	err := d.db.QueryRow(`
+		SELECT next_run_at FROM scheduled_messages
+		WHERE status = ? AND next_run_at IS NOT NULL
+		ORDER BY next_run_at
+		LIMIT 1
+	`, models.ScheduleActive).Scan(&next)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return timeOrNil(next), nil
+}
+
+// ClaimScheduleRun registra o início de uma execução e já avança o
+// agendamento para a próxima (ou para status, no envio único). Retorna false
+// se ele deixou de estar ativo, como após um cancelamento.
+func (d *Database) ClaimScheduleRun(id string, runAt time.Time, next *time.Time, status string) (bool, error) {
+	result, err := d.db.Exec(`
+		UPDATE scheduled_messages
+		SET status = ?, next_run_at = ?, last_run_at = ?, run_count = run_count + 1, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, status, utcOrNil(next), runAt.UTC(), time.Now().UTC(), id, models.ScheduleActive)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// RecordScheduleResult grava o resultado da última execução: a mensagem
+// criada na fila de envio ou o erro
+func (d *Database) RecordScheduleResult(id, messageID, lastError string) error {
+	_, err := d.db.Exec(`UPDATE scheduled_messages SET last_message_id = ?, last_error = ?, updated_at = ? WHERE id = ?`,
+		messageID, lastError, time.Now().UTC(), id)
+	return err
+}
+
+// FailSchedule marca como falho um envio único já executado
+func (d *Database) FailSchedule(id string) error {
+	_, err := d.db.Exec(`UPDATE scheduled_messages SET status = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		models.ScheduleFailed, time.Now().UTC(), id, models.ScheduleCompleted)
+	return err
+}
+
+// CancelSchedule cancela um agendamento ativo do tenant. Retorna
+// ErrScheduleNotFound se ele não existir ou não estiver ativo.
+func (d *Database) CancelSchedule(tenantID, id string) error {
+	result, err := d.db.Exec(`
+		UPDATE scheduled_messages SET status = ?, next_run_at = NULL, updated_at = ?
+		WHERE tenant_id = ? AND id = ? AND status = ?
+	`, models.ScheduleCancelled, time.Now().UTC(), tenantID, id, models.ScheduleActive)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+func (d *Database) querySchedules(query string, args ...interface{}) ([]models.ScheduledMessage, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []models.ScheduledMessage{}
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *s)
+	}
+	return schedules, rows.Err()
+}
+
+func scanSchedule(row rowScanner) (*models.ScheduledMessage, error) {
+	var (
+		s                        models.ScheduledMessage
+		variables                string
+		sendAt, nextRun, lastRun sql.NullTime
+	)
+	err := row.Scan(&s.ID, &s.SessionID, &s.TenantID, &s.PhoneNumber, &s.Body, &s.TemplateID, &variables, &sendAt, &s.Cron,
+		&s.Timezone, &s.Status, &nextRun, &lastRun, &s.LastMessageID, &s.LastError, &s.RunCount, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if variables != "" {
+		if err := json.Unmarshal([]byte(variables), &s.Variables); err != nil {
+			return nil, err
+		}
+	}
+	s.SendAt = timeOrNil(sendAt)
+	s.NextRunAt = timeOrNil(nextRun)
+	s.LastRunAt = timeOrNil(lastRun)
+	return &s, nil
+}
+
+// utcOrNil converte um horário opcional para gravação em UTC
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func timeOrNil(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
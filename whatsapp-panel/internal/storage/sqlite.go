@@ -1,20 +1,38 @@
 package storage
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+
+	"whatsapp-panel/internal/models"
 )
 
 type Database struct {
 	db *sql.DB
 }
 
+var (
+	// ErrSessionNotFound indica que a sessão não existe em whatsapp_sessions
+	ErrSessionNotFound = errors.New("sessão não encontrada")
+	// ErrTenantNotFound indica que o tenant (ou a chave de API) não existe
+	ErrTenantNotFound = errors.New("workspace não encontrado")
+	// ErrSessionExists indica que já existe uma sessão com o mesmo ID
+	ErrSessionExists = errors.New("sessão já existe")
+)
+
 // NewDatabase cria uma nova instância do banco de dados
 func NewDatabase(dbPath string) (*Database, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	// Chaves estrangeiras ligadas em toda conexão (ex: ON DELETE CASCADE de session_stats)
+	db, err := sql.Open("sqlite3", withForeignKeys(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("erro ao abrir banco de dados: %v", err)
 	}
@@ -32,6 +50,14 @@ func NewDatabase(dbPath string) (*Database, error) {
 	return &Database{db: db}, nil
 }
 
+// withForeignKeys acrescenta ao DSN o parâmetro que ativa as chaves estrangeiras
+func withForeignKeys(dsn string) string {
+	if strings.Contains(dsn, "?") {
+		return dsn + "&_foreign_keys=on"
+	}
+	return dsn + "?_foreign_keys=on"
+}
+
 // createTables cria todas as tabelas necessárias no banco de dados
 func createTables(db *sql.DB) error {
 	_, err := db.Exec(`
@@ -49,6 +75,67 @@ func createTables(db *sql.DB) error {
 		return err
 	}
 
+	if err := ensureColumn(db, "whatsapp_sessions", "notes", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	// Proxy de saída da sessão (URL http, https ou socks5, com credenciais)
+	if err := ensureColumn(db, "whatsapp_sessions", "proxy_url", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	// Limites de envio próprios da sessão, em JSON ('' = padrão do servidor)
+	if err := ensureColumn(db, "whatsapp_sessions", "rate_limits", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS tenants (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			api_key_hash TEXT NOT NULL DEFAULT '',
+			max_sessions INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_tenants_api_key ON tenants (api_key_hash) WHERE api_key_hash != ''`)
+	if err != nil {
+		return err
+	}
+
+	// O workspace padrão recebe as sessões criadas antes dos workspaces
+	_, err = db.Exec(
+		`INSERT OR IGNORE INTO tenants (id, name, max_sessions, created_at) VALUES (?, ?, 0, ?)`,
+		models.DefaultTenantID, "Padrão", time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := ensureColumn(db, "whatsapp_sessions", "tenant_id", fmt.Sprintf("TEXT NOT NULL DEFAULT '%s'", models.DefaultTenantID)); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_whatsapp_sessions_tenant ON whatsapp_sessions (tenant_id)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS session_tags (
+			session_id TEXT NOT NULL,
+			tag TEXT NOT NULL COLLATE NOCASE,
+			PRIMARY KEY (session_id, tag)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
 	_, err = db.Exec(`
 		CREATE TABLE IF NOT EXISTS session_stats (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -56,6 +143,7 @@ func createTables(db *sql.DB) error {
 			contacts INTEGER NOT NULL DEFAULT 0,
 			groups INTEGER NOT NULL DEFAULT 0,
 			conversations INTEGER NOT NULL DEFAULT 0,
+			message_count INTEGER NOT NULL DEFAULT 0,
 			updated_at TIMESTAMP NOT NULL,
 			FOREIGN KEY (session_id) REFERENCES whatsapp_sessions (id) ON DELETE CASCADE
 		)
@@ -64,59 +152,445 @@ func createTables(db *sql.DB) error {
 		return err
 	}
 
+	if err := ensureColumn(db, "session_stats", "message_count", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// Bancos antigos não tinham session_id único, o que quebrava o upsert de
+	// UpdateSessionStats. Mantém apenas a linha mais recente de cada sessão
+	// antes de criar o índice.
+	_, err = db.Exec(`DELETE FROM session_stats WHERE id NOT IN (SELECT MAX(id) FROM session_stats GROUP BY session_id)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_session_stats_session ON session_stats (session_id)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS session_status_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id TEXT NOT NULL,
+			from_status TEXT NOT NULL,
+			to_status TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_session_status_history_session ON session_status_history (session_id, created_at)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS reconcile_audit (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_id TEXT NOT NULL,
+			action TEXT NOT NULL,
+			session_id TEXT NOT NULL,
+			tenant_id TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL DEFAULT '',
+			dry_run BOOLEAN NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_reconcile_audit_created ON reconcile_audit (created_at)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS outbound_messages (
+			id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL,
+			tenant_id TEXT NOT NULL,
+			phone_number TEXT NOT NULL,
+			body TEXT NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			wa_message_id TEXT NOT NULL DEFAULT '',
+			next_attempt_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			sent_at TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_outbound_messages_session ON outbound_messages (session_id, status, next_attempt_at)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS campaigns (
+			id TEXT PRIMARY KEY,
+			tenant_id TEXT NOT NULL,
+			session_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			message TEXT NOT NULL,
+			status TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			finished_at TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_campaigns_tenant ON campaigns (tenant_id, created_at)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS campaign_recipients (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			campaign_id TEXT NOT NULL,
+			phone_number TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			fields TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			wa_message_id TEXT NOT NULL DEFAULT '',
+			sent_at TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_campaign_recipients_campaign ON campaign_recipients (campaign_id, status, id)`)
+	if err != nil {
+		return err
+	}
+
+	if err := ensureColumn(db, "campaigns", "template_id", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS message_templates (
+			id TEXT PRIMARY KEY,
+			tenant_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			body TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_message_templates_name ON message_templates (tenant_id, name)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS scheduled_messages (
+			id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL,
+			tenant_id TEXT NOT NULL,
+			phone_number TEXT NOT NULL,
+			body TEXT NOT NULL DEFAULT '',
+			template_id TEXT NOT NULL DEFAULT '',
+			variables TEXT NOT NULL DEFAULT '',
+			send_at TIMESTAMP,
+			cron TEXT NOT NULL DEFAULT '',
+			timezone TEXT NOT NULL,
+			status TEXT NOT NULL,
+			next_run_at TIMESTAMP,
+			last_run_at TIMESTAMP,
+			last_message_id TEXT NOT NULL DEFAULT '',
+			last_error TEXT NOT NULL DEFAULT '',
+			run_count INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_scheduled_messages_next ON scheduled_messages (status, next_run_at)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS messages (
+			id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL,
+			tenant_id TEXT NOT NULL,
+			wa_message_id TEXT NOT NULL DEFAULT '',
+			chat_jid TEXT NOT NULL DEFAULT '',
+			phone_number TEXT NOT NULL DEFAULT '',
+			type TEXT NOT NULL,
+			body TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			sent_at TIMESTAMP,
+			delivered_at TIMESTAMP,
+			read_at TIMESTAMP,
+			played_at TIMESTAMP,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_messages_wa_id ON messages (wa_message_id)`)
+	if err != nil {
+		return err
+	}
+
+	// Histórico de conversas: mensagens recebidas, conteúdo e horário de cada mensagem
+	for _, column := range []struct{ name, definition string }{
+		{"direction", fmt.Sprintf("TEXT NOT NULL DEFAULT '%s'", models.MessageOutbound)},
+		{"sender_jid", "TEXT NOT NULL DEFAULT ''"},
+		{"sender_name", "TEXT NOT NULL DEFAULT ''"},
+		{"media_mimetype", "TEXT NOT NULL DEFAULT ''"},
+		{"media_filename", "TEXT NOT NULL DEFAULT ''"},
+		{"media_size", "INTEGER NOT NULL DEFAULT 0"},
+		{"media_direct_path", "TEXT NOT NULL DEFAULT ''"},
+		{"quoted_message_id", "TEXT NOT NULL DEFAULT ''"},
+		{"timestamp", "TIMESTAMP"},
+	} {
+		if err := ensureColumn(db, "messages", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	// Mensagens gravadas antes do histórico usam o horário de envio
+	_, err = db.Exec(`UPDATE messages SET timestamp = COALESCE(sent_at, created_at) WHERE timestamp IS NULL`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_messages_chat ON messages (session_id, chat_jid, timestamp)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_messages_sent ON messages (session_id, sent_at)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_chat_wa_id ON messages (session_id, chat_jid, wa_message_id) WHERE wa_message_id != ''`)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// ensureColumn adiciona a coluna à tabela caso ela ainda não exista
+func ensureColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
 // Close fecha a conexão com o banco de dados
 func (d *Database) Close() error {
 	return d.db.Close()
 }
 
-// SaveSession armazena informações da sessão no banco de dados
-func (d *Database) SaveSession(id, name, jid, phoneNumber string) error {
+// SaveSession armazena informações da sessão no banco de dados. O tenant só é
+// gravado na criação da linha; uma sessão nunca muda de workspace.
+func (d *Database) SaveSession(id, tenantID, name, jid, phoneNumber, status string) error {
 	now := time.Now()
 	_, err := d.db.Exec(
-		`INSERT INTO whatsapp_sessions (id, name, jid, phone_number, connected_at, last_active, status) 
-		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		`INSERT INTO whatsapp_sessions (id, tenant_id, name, jid, phone_number, connected_at, last_active, status) 
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		 ON CONFLICT(id) DO UPDATE SET 
 			name = excluded.name,
 			jid = excluded.jid,
 			phone_number = excluded.phone_number,
 			last_active = excluded.last_active,
 			status = excluded.status`,
-		id, name, jid, phoneNumber, now, now, "connected",
+		id, tenantID, name, jid, phoneNumber, now, now, status,
 	)
 	return err
 }
 
+// RecordStatusTransition grava o novo status da sessão e registra a transição
+// no histórico. Sessões ainda sem linha em whatsapp_sessions (pendentes) têm
+// apenas o histórico registrado.
+func (d *Database) RecordStatusTransition(sessionID, from, to, reason string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.Exec(
+		`UPDATE whatsapp_sessions SET status = ?, last_active = ? WHERE id = ?`,
+		to, now, sessionID,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO session_status_history (session_id, from_status, to_status, reason, created_at)
+		 VALUES (?, ?, ?, ?, ?)`,
+		sessionID, from, to, reason, now,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetStatusHistory retorna as transições de status de uma sessão, da mais recente para a mais antiga
+func (d *Database) GetStatusHistory(sessionID string) ([]models.StatusTransition, error) {
+	rows, err := d.db.Query(`
+		SELECT session_id, from_status, to_status, reason, created_at
+		FROM session_status_history
+		WHERE session_id = ?
+		ORDER BY created_at DESC, id DESC
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.StatusTransition{}
+	for rows.Next() {
+		var t models.StatusTransition
+		if err := rows.Scan(&t.SessionID, &t.From, &t.To, &t.Reason, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, t)
+	}
+	return history, rows.Err()
+}
+
+// LinkSession atualiza JID, telefone e status da linha de uma sessão
+// restaurada, sem sobrescrever o nome já cadastrado. Nunca cria a linha:
+// sessões sem cadastro retornam ErrSessionNotFound.
+func (d *Database) LinkSession(id, jid, phoneNumber, status string) error {
+	result, err := d.db.Exec(
+		`UPDATE whatsapp_sessions SET jid = ?, phone_number = ?, last_active = ?, status = ? WHERE id = ?`,
+		jid, phoneNumber, time.Now(), status, id,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
 // UpdateSessionStats atualiza as estatísticas de uma sessão
-func (d *Database) UpdateSessionStats(sessionID string, contacts, groups, conversations int) error {
+func (d *Database) UpdateSessionStats(sessionID string, contacts, groups, conversations int, messageCount int64) error {
 	now := time.Now()
 	_, err := d.db.Exec(
-		`INSERT INTO session_stats (session_id, contacts, groups, conversations, updated_at)
-		 VALUES (?, ?, ?, ?, ?)
+		`INSERT INTO session_stats (session_id, contacts, groups, conversations, message_count, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
 		 ON CONFLICT(session_id) DO UPDATE SET
 		   contacts = excluded.contacts,
 		   groups = excluded.groups,
 		   conversations = excluded.conversations,
+		   message_count = excluded.message_count,
 		   updated_at = excluded.updated_at`,
-		sessionID, contacts, groups, conversations, now,
+		sessionID, contacts, groups, conversations, messageCount, now,
 	)
 	return err
 }
 
-// GetAllSessions retorna todas as sessões ativas
-func (d *Database) GetAllSessions() ([]map[string]interface{}, error) {
-	rows, err := d.db.Query(`
-		SELECT s.id, s.name, s.jid, s.phone_number, s.connected_at, s.last_active, s.status,
+// GetSessionStats retorna as estatísticas gravadas de uma sessão (zeradas se não houver)
+func (d *Database) GetSessionStats(sessionID string) (models.Stats, error) {
+	var stats models.Stats
+	err := d.db.QueryRow(
+		`SELECT contacts, groups, conversations, message_count FROM session_stats WHERE session_id = ?`,
+		sessionID,
+	).Scan(&stats.Contacts, &stats.Groups, &stats.Conversations, &stats.MessageCount)
+	if err == sql.ErrNoRows {
+		return stats, nil
+	}
+	return stats, err
+}
+
+// MarkSessionConnected atualiza os horários de conexão e atividade da sessão
+func (d *Database) MarkSessionConnected(id string) error {
+	now := time.Now()
+	_, err := d.db.Exec(
+		`UPDATE whatsapp_sessions SET connected_at = ?, last_active = ? WHERE id = ?`,
+		now, now, id,
+	)
+	return err
+}
+
+// GetAllSessions retorna todas as sessões, em qualquer status
+func (d *Database) GetAllSessions(tenantID string, tags ...string) ([]map[string]interface{}, error) {
+	query := `
+		SELECT s.id, s.name, s.jid, s.phone_number, s.connected_at, s.last_active, s.status, s.notes,
 		       COALESCE(st.contacts, 0) as contacts,
 		       COALESCE(st.groups, 0) as groups,
-		       COALESCE(st.conversations, 0) as conversations
+		       COALESCE(st.conversations, 0) as conversations,
+		       COALESCE(st.message_count, 0) as message_count
 		FROM whatsapp_sessions s
 		LEFT JOIN session_stats st ON s.id = st.session_id
-		WHERE s.status = 'connected'
-		ORDER BY s.connected_at DESC
-	`)
+		WHERE s.tenant_id = ?`
+	args := []interface{}{tenantID}
+	// Cada tag informada restringe o resultado: a sessão precisa ter todas
+	for _, tag := range tags {
+		query += ` AND EXISTS (SELECT 1 FROM session_tags t WHERE t.session_id = s.id AND t.tag = ?)`
+		args = append(args, tag)
+	}
+	query += ` ORDER BY s.connected_at DESC`
+
+	sessionTags, err := d.getTagsBySession()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -125,13 +599,18 @@ func (d *Database) GetAllSessions() ([]map[string]interface{}, error) {
 	sessions := []map[string]interface{}{}
 	for rows.Next() {
 		var (
-			id, name, jid, phoneNumber, status string
-			connectedAt, lastActive            time.Time
-			contacts, groups, conversations    int
+			id, name, jid, phoneNumber, status, notes string
+			connectedAt, lastActive                   time.Time
+			contacts, groups, conversations           int
+			messageCount                              int
 		)
-		if err := rows.Scan(&id, &name, &jid, &phoneNumber, &connectedAt, &lastActive, &status, &contacts, &groups, &conversations); err != nil {
+		if err := rows.Scan(&id, &name, &jid, &phoneNumber, &connectedAt, &lastActive, &status, &notes, &contacts, &groups, &conversations, &messageCount); err != nil {
 			return nil, err
 		}
+		tags := sessionTags[id]
+		if tags == nil {
+			tags = []string{}
+		}
 		session := map[string]interface{}{
 			"ID":          id,
 			"Name":        name,
@@ -140,19 +619,513 @@ func (d *Database) GetAllSessions() ([]map[string]interface{}, error) {
 			"ConnectedAt": connectedAt.Format("02/01/2006 15:04:05"),
 			"LastActive":  lastActive.Format("02/01/2006 15:04:05"),
 			"Status":      status,
+			"Notes":       notes,
+			"Tags":        tags,
 			"Stats": map[string]int{
 				"Contacts":      contacts,
 				"Groups":        groups,
 				"Conversations": conversations,
+				"MessageCount":  messageCount,
 			},
 		}
 		sessions = append(sessions, session)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 	return sessions, nil
 }
 
-// DeleteSession remove uma sessão do banco de dados
-func (d *Database) DeleteSession(id string) error {
-	_, err := d.db.Exec("DELETE FROM whatsapp_sessions WHERE id = ?", id)
+// getTagsBySession retorna as tags de todas as sessões, em ordem alfabética
+func (d *Database) getTagsBySession() (map[string][]string, error) {
+	rows, err := d.db.Query(`SELECT session_id, tag FROM session_tags ORDER BY tag`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := map[string][]string{}
+	for rows.Next() {
+		var sessionID, tag string
+		if err := rows.Scan(&sessionID, &tag); err != nil {
+			return nil, err
+		}
+		tags[sessionID] = append(tags[sessionID], tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetAllTags retorna as tags em uso por alguma sessão do tenant, sem repetição
+func (d *Database) GetAllTags(tenantID string) ([]string, error) {
+	rows, err := d.db.Query(
+		`SELECT DISTINCT t.tag FROM session_tags t
+		 JOIN whatsapp_sessions s ON s.id = t.session_id
+		 WHERE s.tenant_id = ?
+		 ORDER BY t.tag`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// UpdateSessionMetadata altera nome, notas e tags de uma sessão. Campos nil
+// são mantidos; tags não nil substituem todas as tags atuais. Retorna
+// ErrSessionNotFound se a sessão não existir no tenant.
+func (d *Database) UpdateSessionMetadata(tenantID, id string, name, notes *string, tags []string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM whatsapp_sessions WHERE id = ? AND tenant_id = ?`, id, tenantID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrSessionNotFound
+	}
+
+	if name != nil {
+		if _, err := tx.Exec(`UPDATE whatsapp_sessions SET name = ? WHERE id = ?`, *name, id); err != nil {
+			return err
+		}
+	}
+	if notes != nil {
+		if _, err := tx.Exec(`UPDATE whatsapp_sessions SET notes = ? WHERE id = ?`, *notes, id); err != nil {
+			return err
+		}
+	}
+	if tags != nil {
+		if _, err := tx.Exec(`DELETE FROM session_tags WHERE session_id = ?`, id); err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO session_tags (session_id, tag) VALUES (?, ?)`, id, tag); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (d *Database) DeleteSession(tenantID, id string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// session_stats é removida pelo ON DELETE CASCADE
+	result, err := tx.Exec("DELETE FROM whatsapp_sessions WHERE id = ? AND tenant_id = ?", id, tenantID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrSessionNotFound
+	}
+	for _, table := range []string{"session_tags", "outbound_messages", "scheduled_messages", "messages"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE session_id = ?", id); err != nil {
+			return err
+		}
+	}
+	if err := deleteSessionCampaigns(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetSession retorna a sessão do tenant com tags e estatísticas, ou
+// ErrSessionNotFound
+func (d *Database) GetSession(tenantID, id string) (*models.Session, error) {
+	s := &models.Session{ID: id, Tags: []string{}}
+	var rateLimits string
+	err := d.db.QueryRow(`
+		SELECT s.name, s.jid, COALESCE(s.phone_number, ''), s.connected_at, s.last_active, s.status, s.notes, s.proxy_url, s.rate_limits,
+		       COALESCE(st.contacts, 0), COALESCE(st.groups, 0), COALESCE(st.conversations, 0), COALESCE(st.message_count, 0)
+		FROM whatsapp_sessions s
+		LEFT JOIN session_stats st ON s.id = st.session_id
+		WHERE s.id = ? AND s.tenant_id = ?`,
+		id, tenantID,
+	).Scan(&s.Name, &s.JID, &s.PhoneNumber, &s.ConnectedAt, &s.LastActive, &s.Status, &s.Notes, &s.Proxy, &rateLimits,
+		&s.Stats.Contacts, &s.Stats.Groups, &s.Stats.Conversations, &s.Stats.MessageCount)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.RateLimits, err = decodeRateLimits(rateLimits); err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query(`SELECT tag FROM session_tags WHERE session_id = ? ORDER BY tag`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		s.Tags = append(s.Tags, tag)
+	}
+	return s, rows.Err()
+}
+
+// InsertSession grava uma sessão completa (linha, tags e estatísticas) no
+// tenant, como na importação de um pacote. Retorna ErrSessionExists se o ID
+// já estiver em uso em qualquer tenant.
+func (d *Database) InsertSession(tenantID string, s *models.Session) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM whatsapp_sessions WHERE id = ?`, s.ID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrSessionExists
+	}
+
+	rateLimits, err := encodeRateLimits(s.RateLimits)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO whatsapp_sessions (id, tenant_id, name, jid, phone_number, connected_at, last_active, status, notes, proxy_url, rate_limits)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, tenantID, s.Name, s.JID, s.PhoneNumber, s.ConnectedAt, s.LastActive, s.Status, s.Notes, s.Proxy, rateLimits,
+	); err != nil {
+		return err
+	}
+
+	// Tags de uma sessão removida com o mesmo ID não devem reaparecer
+	if _, err := tx.Exec(`DELETE FROM session_tags WHERE session_id = ?`, s.ID); err != nil {
+		return err
+	}
+	for _, tag := range s.Tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO session_tags (session_id, tag) VALUES (?, ?)`, s.ID, tag); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO session_stats (session_id, contacts, groups, conversations, message_count, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(session_id) DO UPDATE SET
+		   contacts = excluded.contacts,
+		   groups = excluded.groups,
+		   conversations = excluded.conversations,
+		   message_count = excluded.message_count,
+		   updated_at = excluded.updated_at`,
+		s.ID, s.Stats.Contacts, s.Stats.Groups, s.Stats.Conversations, s.Stats.MessageCount, time.Now(),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetSessionProxy grava o proxy de saída da sessão do tenant ("" remove), ou
+// retorna ErrSessionNotFound
+func (d *Database) SetSessionProxy(tenantID, id, proxyURL string) error {
+	result, err := d.db.Exec(`UPDATE whatsapp_sessions SET proxy_url = ? WHERE id = ? AND tenant_id = ?`, proxyURL, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// GetSessionProxy retorna o proxy de saída da sessão, ou ErrSessionNotFound
+func (d *Database) GetSessionProxy(id string) (string, error) {
+	var proxyURL string
+	err := d.db.QueryRow(`SELECT proxy_url FROM whatsapp_sessions WHERE id = ?`, id).Scan(&proxyURL)
+	if err == sql.ErrNoRows {
+		return "", ErrSessionNotFound
+	}
+	return proxyURL, err
+}
+
+// SetSessionRateLimits grava os limites de envio da sessão do tenant (nil
+// volta ao padrão do servidor), ou retorna ErrSessionNotFound
+func (d *Database) SetSessionRateLimits(tenantID, id string, limits *models.RateLimits) error {
+	value, err := encodeRateLimits(limits)
+	if err != nil {
+		return err
+	}
+	result, err := d.db.Exec(`UPDATE whatsapp_sessions SET rate_limits = ? WHERE id = ? AND tenant_id = ?`, value, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// GetSessionRateLimits retorna os limites de envio próprios da sessão (nil se
+// ela usa o padrão do servidor), ou ErrSessionNotFound
+func (d *Database) GetSessionRateLimits(id string) (*models.RateLimits, error) {
+	var value string
+	err := d.db.QueryRow(`SELECT rate_limits FROM whatsapp_sessions WHERE id = ?`, id).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeRateLimits(value)
+}
+
+func encodeRateLimits(limits *models.RateLimits) (string, error) {
+	if limits == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(limits)
+	return string(data), err
+}
+
+func decodeRateLimits(value string) (*models.RateLimits, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var limits models.RateLimits
+	if err := json.Unmarshal([]byte(value), &limits); err != nil {
+		return nil, fmt.Errorf("limites de envio inválidos: %v", err)
+	}
+	return &limits, nil
+}
+
+// GetSessionTenant retorna o tenant dono da sessão, ou ErrSessionNotFound
+func (d *Database) GetSessionTenant(id string) (string, error) {
+	var tenantID string
+	err := d.db.QueryRow(`SELECT tenant_id FROM whatsapp_sessions WHERE id = ?`, id).Scan(&tenantID)
+	if err == sql.ErrNoRows {
+		return "", ErrSessionNotFound
+	}
+	return tenantID, err
+}
+
+// ListSessionRefs retorna o tenant e o status de cada sessão gravada, de todos
+// os workspaces
+func (d *Database) ListSessionRefs() (map[string]models.SessionRef, error) {
+	rows, err := d.db.Query(`SELECT id, tenant_id, status FROM whatsapp_sessions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refs := make(map[string]models.SessionRef)
+	for rows.Next() {
+		var id string
+		var ref models.SessionRef
+		if err := rows.Scan(&id, &ref.TenantID, &ref.Status); err != nil {
+			return nil, err
+		}
+		refs[id] = ref
+	}
+	return refs, rows.Err()
+}
+
+// CountActiveSessions conta as sessões gravadas do tenant que ainda ocupam a
+// cota, ou seja, que não foram deslogadas
+func (d *Database) CountActiveSessions(tenantID string) (int, error) {
+	var count int
+	err := d.db.QueryRow(
+		`SELECT COUNT(*) FROM whatsapp_sessions WHERE tenant_id = ? AND status != ?`,
+		tenantID, models.StatusLoggedOut,
+	).Scan(&count)
+	return count, err
+}
+
+// CreateTenant cria um workspace com uma nova chave de API. A chave só é
+// devolvida aqui; o banco guarda apenas seu hash.
+func (d *Database) CreateTenant(id, name string, maxSessions int) (*models.Tenant, error) {
+	apiKey, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	tenant := &models.Tenant{
+		ID:          id,
+		Name:        name,
+		APIKey:      apiKey,
+		MaxSessions: maxSessions,
+		CreatedAt:   time.Now(),
+	}
+	_, err = d.db.Exec(
+		`INSERT INTO tenants (id, name, api_key_hash, max_sessions, created_at) VALUES (?, ?, ?, ?, ?)`,
+		tenant.ID, tenant.Name, hashAPIKey(apiKey), tenant.MaxSessions, tenant.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// RotateTenantKey gera uma nova chave de API para o tenant, invalidando a anterior
+func (d *Database) RotateTenantKey(id string) (string, error) {
+	apiKey, err := newAPIKey()
+	if err != nil {
+		return "", err
+	}
+	result, err := d.db.Exec(`UPDATE tenants SET api_key_hash = ? WHERE id = ?`, hashAPIKey(apiKey), id)
+	if err != nil {
+		return "", err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return "", err
+	} else if n == 0 {
+		return "", ErrTenantNotFound
+	}
+	return apiKey, nil
+}
+
+// SetTenantQuota altera o número máximo de sessões do tenant (0 = sem limite)
+func (d *Database) SetTenantQuota(id string, maxSessions int) error {
+	result, err := d.db.Exec(`UPDATE tenants SET max_sessions = ? WHERE id = ?`, maxSessions, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTenantNotFound
+	}
+	return nil
+}
+
+// GetTenant retorna um tenant pelo ID, ou ErrTenantNotFound
+func (d *Database) GetTenant(id string) (*models.Tenant, error) {
+	return d.scanTenant(d.db.QueryRow(
+		`SELECT id, name, max_sessions, created_at FROM tenants WHERE id = ?`, id,
+	))
+}
+
+// GetTenantByAPIKey retorna o tenant dono da chave de API, ou ErrTenantNotFound
+func (d *Database) GetTenantByAPIKey(apiKey string) (*models.Tenant, error) {
+	if apiKey == "" {
+		return nil, ErrTenantNotFound
+	}
+	return d.scanTenant(d.db.QueryRow(
+		`SELECT id, name, max_sessions, created_at FROM tenants WHERE api_key_hash = ?`, hashAPIKey(apiKey),
+	))
+}
+
+// CountTenantKeys conta os workspaces com chave de API, ou seja, os que
+// conseguem acessar o painel sem a chave de administrador
+func (d *Database) CountTenantKeys() (int, error) {
+	var count int
+	err := d.db.QueryRow(`SELECT COUNT(*) FROM tenants WHERE api_key_hash != ''`).Scan(&count)
+	return count, err
+}
+
+// ListTenants retorna todos os tenants, em ordem de criação
+func (d *Database) ListTenants() ([]models.Tenant, error) {
+	rows, err := d.db.Query(`SELECT id, name, max_sessions, created_at FROM tenants ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tenants := []models.Tenant{}
+	for rows.Next() {
+		var t models.Tenant
+		if err := rows.Scan(&t.ID, &t.Name, &t.MaxSessions, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, t)
+	}
+	return tenants, rows.Err()
+}
+
+func (d *Database) scanTenant(row *sql.Row) (*models.Tenant, error) {
+	var t models.Tenant
+	err := row.Scan(&t.ID, &t.Name, &t.MaxSessions, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrTenantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// newAPIKey gera uma chave de API aleatória
+func newAPIKey() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("erro ao gerar chave de API: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashAPIKey retorna o hash guardado no banco para uma chave de API
+func hashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordReconcileAction grava uma ação do reconciliador na auditoria
+func (d *Database) RecordReconcileAction(a *models.ReconcileAction) error {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	_, err := d.db.Exec(
+		`INSERT INTO reconcile_audit (run_id, action, session_id, tenant_id, reason, dry_run, error, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.RunID, a.Action, a.SessionID, a.TenantID, a.Reason, a.DryRun, a.Error, a.CreatedAt,
+	)
 	return err
 }
+
+// GetReconcileAudit retorna as últimas ações do reconciliador, da mais recente
+// para a mais antiga
+func (d *Database) GetReconcileAudit(limit int) ([]models.ReconcileAction, error) {
+	rows, err := d.db.Query(`
+		SELECT run_id, action, session_id, tenant_id, reason, dry_run, error, created_at
+		FROM reconcile_audit
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	audit := []models.ReconcileAction{}
+	for rows.Next() {
+		var a models.ReconcileAction
+		if err := rows.Scan(&a.RunID, &a.Action, &a.SessionID, &a.TenantID, &a.Reason, &a.DryRun, &a.Error, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		audit = append(audit, a)
+	}
+	return audit, rows.Err()
+}
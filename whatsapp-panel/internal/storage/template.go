@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"whatsapp-panel/internal/models"
+)
+
+var (
+	// ErrTemplateNotFound indica que o modelo não existe no tenant
+	ErrTemplateNotFound = errors.New("modelo não encontrado")
+	// ErrTemplateExists indica que o tenant já tem um modelo com o mesmo nome
+	ErrTemplateExists = errors.New("já existe um modelo com esse nome")
+)
+
+const templateColumns = `id, tenant_id, name, body, created_at, updated_at`
+
+// CreateTemplate grava um modelo de mensagem. Retorna ErrTemplateExists se o
+// nome já estiver em uso no tenant.
+func (d *Database) CreateTemplate(t *models.MessageTemplate) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := templateNameTaken(tx, t.TenantID, t.Name, ""); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	t.CreatedAt, t.UpdatedAt = now, now
+	if _, err := tx.Exec(`INSERT INTO message_templates (`+templateColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		t.ID, t.TenantID, t.Name, t.Body, now, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateTemplate altera o nome e o texto de um modelo do tenant
+func (d *Database) UpdateTemplate(t *models.MessageTemplate) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := templateNameTaken(tx, t.TenantID, t.Name, t.ID); err != nil {
+		return err
+	}
+	t.UpdatedAt = time.Now().UTC()
+	result, err := tx.Exec(`UPDATE message_templates SET name = ?, body = ?, updated_at = ? WHERE tenant_id = ? AND id = ?`,
+		t.Name, t.Body, t.UpdatedAt, t.TenantID, t.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTemplateNotFound
+	}
+	return tx.Commit()
+}
+
+// templateNameTaken retorna ErrTemplateExists se outro modelo do tenant
+// (diferente de exceptID) já usa o nome
+func templateNameTaken(tx *sql.Tx, tenantID, name, exceptID string) error {
+	var exists int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM message_templates WHERE tenant_id = ? AND name = ? AND id != ?`,
+		tenantID, name, exceptID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrTemplateExists
+	}
+	return nil
+}
+
+// GetTemplate retorna um modelo do tenant, ou ErrTemplateNotFound
+func (d *Database) GetTemplate(tenantID, id string) (*models.MessageTemplate, error) {
+	row := d.db.QueryRow(`SELECT `+templateColumns+` FROM message_templates WHERE tenant_id = ? AND id = ?`, tenantID, id)
+	t, err := scanTemplate(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrTemplateNotFound
+	}
+	return t, err
+}
+
+// ListTemplates retorna os modelos do tenant em ordem alfabética
+func (d *Database) ListTemplates(tenantID string) ([]models.MessageTemplate, error) {
+	rows, err := d.db.Query(`SELECT `+templateColumns+` FROM message_templates WHERE tenant_id = ? ORDER BY name`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.MessageTemplate{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+	return templates, rows.Err()
+}
+
+// DeleteTemplate remove um modelo do tenant. Campanhas criadas a partir dele
+// guardam uma cópia do texto e não são afetadas.
+func (d *Database) DeleteTemplate(tenantID, id string) error {
+	result, err := d.db.Exec(`DELETE FROM message_templates WHERE tenant_id = ? AND id = ?`, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+func scanTemplate(row rowScanner) (*models.MessageTemplate, error) {
+	var t models.MessageTemplate
+	if err := row.Scan(&t.ID, &t.TenantID, &t.Name, &t.Body, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
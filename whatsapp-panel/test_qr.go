@@ -9,11 +9,20 @@ import (
 	"os"
 	"time"
 
+	"whatsapp-panel/internal/config"
+	"whatsapp-panel/internal/models"
 	"whatsapp-panel/internal/services/whatsapp"
 	"whatsapp-panel/internal/storage"
 )
 
 func main() {
+	// Carregar configurações (diretório de sessões compartilhado com o servidor)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Println("Erro ao carregar configurações:", err)
+		os.Exit(1)
+	}
+
 	// Criar banco de dados de teste
 	db, err := storage.NewDatabase("test_qr.db")
 	if err != nil {
@@ -23,10 +32,14 @@ func main() {
 	defer db.Close()
 
 	// Criar Manager do WhatsApp
-	mgr := whatsapp.NewManager(db)
+	mgr, err := whatsapp.NewManager(cfg, db)
+	if err != nil {
+		fmt.Println("Erro ao criar gerenciador WhatsApp:", err)
+		os.Exit(1)
+	}
 
 	// Criar novo cliente
-	client, err := mgr.NewClient()
+	client, err := mgr.NewClient(models.DefaultTenantID)
 	if err != nil {
 		fmt.Println("Erro ao criar cliente WhatsApp:", err)
 		os.Exit(1)